@@ -2,11 +2,95 @@ package cobra
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 )
 
 type PositionalArgs func(cmd *Command, args []string) error
 
+// describedArgs is implemented by the argument validators which describe themselves.
+type describedArgs interface {
+	// Describe returns the sentence describing the arguments accepted by the validator,
+	// e.g. "Requires exactly 2 arguments.".
+	Describe() string
+	validate(cmd *Command, args []string) error
+}
+
+// describedValidator is the PositionalArgs of a describedArgs.
+type describedValidator struct {
+	args describedArgs
+}
+
+// describeArgsRequest is passed as the command to the validators of describedValidator
+// by DescribeArgs, which then return a describeArgsResponse instead of validating.
+var describeArgsRequest = &Command{}
+
+type describeArgsResponse struct {
+	args describedArgs
+}
+
+func (r describeArgsResponse) Error() string { return r.args.Describe() }
+
+func (v describedValidator) validate(cmd *Command, args []string) error {
+	if cmd == describeArgsRequest {
+		return describeArgsResponse{v.args}
+	}
+	return v.args.validate(cmd, args)
+}
+
+// newDescribedArgs returns the PositionalArgs validating with args, which DescribeArgs describes.
+func newDescribedArgs(args describedArgs) PositionalArgs {
+	return describedValidator{args}.validate
+}
+
+// DescribeArgs returns the sentence describing the arguments accepted by the validator,
+// e.g. "Requires exactly 2 arguments.", for the documentation. The built-in validators
+// bounding the number of arguments describe themselves; custom ones can be described
+// with WithArgsDescription. It returns an empty string for the other validators, which
+// are never called.
+func DescribeArgs(validator PositionalArgs) string {
+	if validator == nil {
+		return ""
+	}
+	// Functions are not comparable, but all the validators returned by newDescribedArgs
+	// share the code of describedValidator.validate.
+	switch reflect.ValueOf(validator).Pointer() {
+	case reflect.ValueOf(NoArgs).Pointer():
+		return "Accepts no arguments."
+	case reflect.ValueOf(describedValidator{}.validate).Pointer():
+		if response, ok := validator(describeArgsRequest, nil).(describeArgsResponse); ok {
+			return response.args.Describe()
+		}
+	}
+	return ""
+}
+
+// customDescription is a validator described by a custom description.
+type customDescription struct {
+	description string
+	validator   PositionalArgs
+}
+
+func (d customDescription) Describe() string { return d.description }
+
+func (d customDescription) validate(cmd *Command, args []string) error {
+	return d.validator(cmd, args)
+}
+
+// WithArgsDescription returns validator described by description, as returned by
+// DescribeArgs, e.g. "Requires a resource name and an optional version.".
+func WithArgsDescription(description string, validator PositionalArgs) PositionalArgs {
+	return newDescribedArgs(customDescription{description, validator})
+}
+
+// pluralArgs returns "argument" or "arguments" depending on n.
+func pluralArgs(n int) string {
+	if n == 1 {
+		return "argument"
+	}
+	return "arguments"
+}
+
 // Legacy arg validation has the following behaviour:
 // - root commands with no subcommands can take arbitrary arguments
 // - root commands with subcommands will do subcommand validity checking
@@ -56,54 +140,99 @@ func ArbitraryArgs(cmd *Command, args []string) error {
 	return nil
 }
 
+type minimumNArgs int
+
+func (n minimumNArgs) Describe() string {
+	return fmt.Sprintf("Requires at least %d %s.", n, pluralArgs(int(n)))
+}
+
+func (n minimumNArgs) validate(cmd *Command, args []string) error {
+	if len(args) < int(n) {
+		return fmt.Errorf("requires at least %d arg(s), only received %d", n, len(args))
+	}
+	return nil
+}
+
 // MinimumNArgs returns an error if there is not at least N args.
 func MinimumNArgs(n int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
-		if len(args) < n {
-			return fmt.Errorf("requires at least %d arg(s), only received %d", n, len(args))
-		}
-		return nil
+	return newDescribedArgs(minimumNArgs(n))
+}
+
+type maximumNArgs int
+
+func (n maximumNArgs) Describe() string {
+	return fmt.Sprintf("Accepts at most %d %s.", n, pluralArgs(int(n)))
+}
+
+func (n maximumNArgs) validate(cmd *Command, args []string) error {
+	if len(args) > int(n) {
+		return fmt.Errorf("accepts at most %d arg(s), received %d", n, len(args))
 	}
+	return nil
 }
 
 // MaximumNArgs returns an error if there are more than N args.
 func MaximumNArgs(n int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
-		if len(args) > n {
-			return fmt.Errorf("accepts at most %d arg(s), received %d", n, len(args))
-		}
-		return nil
+	return newDescribedArgs(maximumNArgs(n))
+}
+
+type exactArgs int
+
+func (n exactArgs) Describe() string {
+	if n == 0 {
+		return "Accepts no arguments."
 	}
+	return fmt.Sprintf("Requires exactly %d %s.", n, pluralArgs(int(n)))
+}
+
+func (n exactArgs) validate(cmd *Command, args []string) error {
+	if len(args) != int(n) {
+		return fmt.Errorf("accepts %d arg(s), received %d", n, len(args))
+	}
+	return nil
 }
 
 // ExactArgs returns an error if there are not exactly n args.
 func ExactArgs(n int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
-		if len(args) != n {
-			return fmt.Errorf("accepts %d arg(s), received %d", n, len(args))
-		}
-		return nil
+	return newDescribedArgs(exactArgs(n))
+}
+
+type exactValidArgs int
+
+func (n exactValidArgs) Describe() string {
+	return exactArgs(n).Describe()
+}
+
+func (n exactValidArgs) validate(cmd *Command, args []string) error {
+	if err := exactArgs(n).validate(cmd, args); err != nil {
+		return err
 	}
+	return OnlyValidArgs(cmd, args)
 }
 
 // ExactValidArgs returns an error if
 // there are not exactly N positional args OR
 // there are any positional args that are not in the `ValidArgs` field of `Command`
 func ExactValidArgs(n int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
-		if err := ExactArgs(n)(cmd, args); err != nil {
-			return err
-		}
-		return OnlyValidArgs(cmd, args)
+	return newDescribedArgs(exactValidArgs(n))
+}
+
+type rangeArgs struct {
+	min, max int
+}
+
+func (r rangeArgs) Describe() string {
+	return fmt.Sprintf("Requires between %d and %d arguments.", r.min, r.max)
+}
+
+func (r rangeArgs) validate(cmd *Command, args []string) error {
+	if len(args) < r.min || len(args) > r.max {
+		return fmt.Errorf("accepts between %d and %d arg(s), received %d", r.min, r.max, len(args))
 	}
+	return nil
 }
 
 // RangeArgs returns an error if the number of args is not within the expected range.
 func RangeArgs(min int, max int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
-		if len(args) < min || len(args) > max {
-			return fmt.Errorf("accepts between %d and %d arg(s), received %d", min, max, len(args))
-		}
-		return nil
-	}
+	return newDescribedArgs(rangeArgs{min, max})
 }
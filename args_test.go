@@ -285,3 +285,44 @@ func TestChildTakesArgs(t *testing.T) {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 }
+
+func TestDescribeArgs(t *testing.T) {
+	tests := []struct {
+		validator PositionalArgs
+		expected  string
+	}{
+		{NoArgs, "Accepts no arguments."},
+		{ExactArgs(0), "Accepts no arguments."},
+		{ExactArgs(1), "Requires exactly 1 argument."},
+		{ExactArgs(2), "Requires exactly 2 arguments."},
+		{ExactValidArgs(2), "Requires exactly 2 arguments."},
+		{MinimumNArgs(1), "Requires at least 1 argument."},
+		{MaximumNArgs(3), "Accepts at most 3 arguments."},
+		{RangeArgs(1, 2), "Requires between 1 and 2 arguments."},
+		{ArbitraryArgs, ""},
+		{OnlyValidArgs, ""},
+		{nil, ""},
+		{func(cmd *Command, args []string) error { return nil }, ""},
+		// Custom validators are not called to describe them.
+		{func(cmd *Command, args []string) error { panic("validator called") }, ""},
+		{WithArgsDescription("Requires a resource name.", ExactArgs(1)), "Requires a resource name."},
+	}
+	for i, tc := range tests {
+		if got := DescribeArgs(tc.validator); got != tc.expected {
+			t.Errorf("%d: expected %q, got %q", i, tc.expected, got)
+		}
+	}
+}
+
+func TestWithArgsDescriptionValidates(t *testing.T) {
+	c := &Command{Use: "c", Args: WithArgsDescription("Requires a resource name.", ExactArgs(1)), Run: emptyRun}
+
+	_, err := executeCommand(c)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	expected := "accepts 1 arg(s), received 0"
+	if err.Error() != expected {
+		t.Errorf("Expected: %q, got: %q", expected, err.Error())
+	}
+}
@@ -93,7 +93,20 @@ __%[1]s_handle_go_custom_completion()
         directive=0
     fi
     __%[1]s_debug "${FUNCNAME[0]}: the completion directive is: ${directive}"
+
+    # Separate the placeholders (an empty value followed by a tab and a hint)
+    # from the real completions, as they must never be inserted.
+    local line filtered="" placeholders=()
+    while IFS='' read -r line; do
+        if [[ ${line} == $'\t'* ]]; then
+            placeholders+=("${line:1}")
+        else
+            filtered+="${line}"$'\n'
+        fi
+    done <<< "${out}"
+    out=${filtered}
     __%[1]s_debug "${FUNCNAME[0]}: the completions are: ${out[*]}"
+    __%[1]s_debug "${FUNCNAME[0]}: the placeholders are: ${placeholders[*]}"
 
     if [ $((directive & %[3]d)) -ne 0 ]; then
         # Error code.  No completion.
@@ -111,11 +124,27 @@ __%[1]s_handle_go_custom_completion()
                 __%[1]s_debug "${FUNCNAME[0]}: activating no file completion"
                 compopt +o default
             fi
+            # Also prevent the custom functions from completing file names
+            nofilecomp=1
+        fi
+        if [ $((directive & %[6]d)) -ne 0 ]; then
+            if [[ $(type -t compopt) = "builtin" ]]; then
+                # The nosort option is only available in bash >= 4.4
+                __%[1]s_debug "${FUNCNAME[0]}: activating keep order"
+                compopt -o nosort 2>/dev/null
+            fi
         fi
 
         while IFS='' read -r comp; do
             COMPREPLY+=("$comp")
         done < <(compgen -W "${out[*]}" -- "$cur")
+
+        if [ ${#COMPREPLY[@]} -eq 0 ] && [ ${#placeholders[@]} -ne 0 ]; then
+            # Only show the placeholders as guidance; the extra empty entry
+            # prevents bash from inserting a lone placeholder.
+            __%[1]s_debug "${FUNCNAME[0]}: showing placeholders"
+            COMPREPLY=("${placeholders[@]}" "")
+        fi
     fi
 }
 
@@ -200,7 +229,7 @@ __%[1]s_handle_reply()
         done < <(compgen -W "${noun_aliases[*]}" -- "$cur")
     fi
 
-    if [[ ${#COMPREPLY[@]} -eq 0 ]]; then
+    if [[ ${#COMPREPLY[@]} -eq 0 && -z "${nofilecomp}" ]]; then
 		if declare -F __%[1]s_custom_func >/dev/null; then
 			# try command name qualified custom func
 			__%[1]s_custom_func
@@ -344,7 +373,7 @@ __%[1]s_handle_word()
     __%[1]s_handle_word
 }
 
-`, name, ShellCompNoDescRequestCmd, ShellCompDirectiveError, ShellCompDirectiveNoSpace, ShellCompDirectiveNoFileComp))
+`, name, ShellCompNoDescRequestCmd, ShellCompDirectiveError, ShellCompDirectiveNoSpace, ShellCompDirectiveNoFileComp, ShellCompDirectiveKeepOrder))
 }
 
 func writePostscript(buf *bytes.Buffer, name string) {
@@ -370,6 +399,7 @@ func writePostscript(buf *bytes.Buffer, name string) {
     local must_have_one_flag=()
     local must_have_one_noun=()
     local has_completion_function
+    local nofilecomp
     local last_command
     local nouns=()
 
@@ -553,7 +583,7 @@ func writeRequiredNouns(buf *bytes.Buffer, cmd *Command) {
 		value = strings.Split(value, "\t")[0]
 		buf.WriteString(fmt.Sprintf("    must_have_one_noun+=(%q)\n", value))
 	}
-	if cmd.ValidArgsFunction != nil {
+	if _, completionFn := cmd.argsCompletion(); completionFn != nil {
 		buf.WriteString("    has_completion_function=1\n")
 	}
 }
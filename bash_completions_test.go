@@ -220,6 +220,29 @@ func TestBashCompletionHiddenFlag(t *testing.T) {
 	}
 }
 
+func TestBashCompletionInheritedValidArgsFunction(t *testing.T) {
+	rootCmd := &Command{
+		Use: "root",
+		PersistentValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+			return []string{"pod1"}, ShellCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+
+	buf := new(bytes.Buffer)
+	rootCmd.GenBashCompletion(buf)
+	output := buf.String()
+
+	start := strings.Index(output, "_root_child()\n")
+	if start < 0 {
+		t.Fatalf("Expected the completion of child in: %v", output)
+	}
+	end := strings.Index(output[start:], "\n}\n")
+	check(t, output[start:start+end], "has_completion_function=1")
+}
+
 func TestBashCompletionDeprecatedFlag(t *testing.T) {
 	c := &Command{Use: "c", Run: emptyRun}
 
@@ -235,3 +258,45 @@ func TestBashCompletionDeprecatedFlag(t *testing.T) {
 		t.Errorf("expected completion to not include %q flag: Got %v", flagName, output)
 	}
 }
+
+func TestBashCompletionNoFileCompDirective(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	rootCmd := &Command{
+		Use:                    "root",
+		Args:                   NoArgs,
+		Run:                    emptyRun,
+		BashCompletionFunction: `__root_custom_func() { COMPREPLY=( "file.txt" ); }`,
+	}
+	childCmd := &Command{
+		Use: "child",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+			return nil, ShellCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	rootCmd.AddCommand(childCmd)
+
+	buf := new(bytes.Buffer)
+	if err := rootCmd.GenBashCompletion(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Complete "root child " with a stub program returning no choice and the
+	// directive, and print the choices offered. _init_completion stands for the
+	// one of the bash-completion package.
+	script := buf.String() + fmt.Sprintf(`
+root() { printf ':%d\n'; }
+_init_completion() { COMPREPLY=(); cur=""; prev="child"; words=(root child ""); cword=2; }
+__start_root 2>/dev/null
+echo "choices: ${COMPREPLY[*]}"
+`, ShellCompDirectiveNoFileComp)
+	out, err := exec.Command(bash, "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %v\n%s", err, out)
+	}
+	check(t, string(out), "choices: \n")
+}
@@ -35,6 +35,7 @@ var templateFuncs = template.FuncMap{
 	"rpad":                    rpad,
 	"gt":                      Gt,
 	"eq":                      Eq,
+	"flagUsages":              helpFlagUsages,
 }
 
 var initializers []func()
@@ -83,6 +84,45 @@ func OnInitialize(y ...func()) {
 	initializers = append(initializers, y...)
 }
 
+// SaveGlobals saves the package-level state of cobra and returns a function restoring
+// it, so that a test changing it does not affect the others:
+//
+//	restore := cobra.SaveGlobals()
+//	defer restore()
+//	cobra.EnablePrefixMatching = true
+//
+// It covers EnablePrefixMatching, EnableCommandSorting, MousetrapHelpText,
+// MousetrapDisplayDuration, EnableBoolFlagSwitches and FlagTypeNames, as well as the
+// template functions added with AddTemplateFunc(s) and the initializers added with
+// OnInitialize. The flag completion functions, which belong to their flags, are not.
+func SaveGlobals() func() {
+	prefixMatching := EnablePrefixMatching
+	commandSorting := EnableCommandSorting
+	mousetrapHelpText := MousetrapHelpText
+	mousetrapDisplayDuration := MousetrapDisplayDuration
+	boolFlagSwitches := EnableBoolFlagSwitches
+	flagTypeNames := make(map[string]string, len(FlagTypeNames))
+	for k, v := range FlagTypeNames {
+		flagTypeNames[k] = v
+	}
+	funcs := make(template.FuncMap, len(templateFuncs))
+	for k, v := range templateFuncs {
+		funcs[k] = v
+	}
+	inits := append([]func(){}, initializers...)
+
+	return func() {
+		EnablePrefixMatching = prefixMatching
+		EnableCommandSorting = commandSorting
+		MousetrapHelpText = mousetrapHelpText
+		MousetrapDisplayDuration = mousetrapDisplayDuration
+		EnableBoolFlagSwitches = boolFlagSwitches
+		FlagTypeNames = flagTypeNames
+		templateFuncs = funcs
+		initializers = inits
+	}
+}
+
 // FIXME Gt is unused by cobra and should be removed in a version 2. It exists only for compatibility with users of cobra.
 
 // Gt takes two types and checks whether the first type is greater than the second. In case of types Arrays, Chans,
@@ -205,3 +245,85 @@ func stringInSlice(a string, list []string) bool {
 	}
 	return false
 }
+
+// shellSplit splits s into words following POSIX shell quoting rules.
+// Single quotes preserve everything literally, double quotes allow
+// backslash escaping of '"', '\', '$' and '`', and an unquoted backslash
+// escapes the following character.
+func shellSplit(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		escaped bool
+		quote   rune
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("\"$\\`\n", r) {
+				word.WriteRune('\\')
+			}
+			// A backslash-newline is a line continuation, which does not start a word.
+			if r != '\n' {
+				word.WriteRune(r)
+				inWord = true
+			}
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("unterminated escape at end of %q", s)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unbalanced %c quote in %q", quote, s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// shellQuote quotes s so that it is read back as a single word by a POSIX shell.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-+=:,./@%", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
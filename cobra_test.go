@@ -1,6 +1,7 @@
 package cobra
 
 import (
+	"strings"
 	"testing"
 	"text/template"
 )
@@ -20,3 +21,28 @@ func TestAddTemplateFunctions(t *testing.T) {
 		t.Errorf("Expected UsageString: %v\nGot: %v", expected, got)
 	}
 }
+
+func TestSaveGlobals(t *testing.T) {
+	restore := SaveGlobals()
+	EnablePrefixMatching = true
+	EnableCommandSorting = false
+	MousetrapHelpText = ""
+	EnableBoolFlagSwitches = true
+	FlagTypeNames["duration"] = "durée"
+	AddTemplateFunc("shout", strings.ToUpper)
+	OnInitialize(func() {})
+	restore()
+
+	if EnablePrefixMatching || !EnableCommandSorting || MousetrapHelpText == "" || EnableBoolFlagSwitches {
+		t.Error("Expected the global variables to be restored")
+	}
+	if _, ok := FlagTypeNames["duration"]; ok {
+		t.Error("Expected FlagTypeNames to be restored")
+	}
+	if _, ok := templateFuncs["shout"]; ok {
+		t.Error("Expected the template functions to be restored")
+	}
+	if len(initializers) != 0 {
+		t.Errorf("Expected the initializers to be restored, got %d", len(initializers))
+	}
+}
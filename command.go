@@ -18,12 +18,18 @@ package cobra
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	flag "github.com/spf13/pflag"
 )
@@ -31,6 +37,67 @@ import (
 // FParseErrWhitelist configures Flag parse errors to be ignored
 type FParseErrWhitelist flag.ParseErrorsWhitelist
 
+// UsageError wraps the errors caused by an incorrect usage of a command, such as
+// unknown commands, invalid flags or arguments, or missing required flags, as
+// opposed to errors returned by the application while running the command.
+// Use IsUsageError to tell them apart, e.g. to pick an exit code.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// IsUsageError always returns true; it allows matching usage errors through an interface.
+func (e *UsageError) IsUsageError() bool {
+	return true
+}
+
+// IsUsageError returns true if err, or any error it wraps, was caused by an incorrect
+// usage of a command. Conventionally, such errors exit with code 2 and show the usage,
+// while application errors exit with code 1.
+func IsUsageError(err error) bool {
+	for ; err != nil; err = unwrapError(err) {
+		if usageErr, ok := err.(interface{ IsUsageError() bool }); ok && usageErr.IsUsageError() {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapError returns the error wrapped by err, through its Unwrap or Cause method,
+// or nil if it does not wrap one. It stands for errors.Unwrap, which needs Go 1.13.
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
+	case interface{ Cause() error }:
+		return e.Cause()
+	}
+	return nil
+}
+
+// newUsageError wraps err into a UsageError, unless it is nil or already one.
+func newUsageError(err error) error {
+	if err == nil || IsUsageError(err) {
+		return err
+	}
+	return &UsageError{Err: err}
+}
+
+// Group is a group of subcommands listed together in the help of their parent,
+// under a title.
+type Group struct {
+	ID    string
+	Title string
+}
+
 // Command is just that, a command for your application.
 // E.g.  'go run ...' - 'run' is the command. Cobra requires
 // you to define the usage and description as part of your command
@@ -42,6 +109,10 @@ type Command struct {
 	// Aliases is an array of aliases that can be used instead of the first word in Use.
 	Aliases []string
 
+	// GroupID is the ID of the group, added with AddGroup to the parent, under which
+	// the command is listed in the help of its parent.
+	GroupID string
+
 	// SuggestFor is an array of command names for which this command will be suggested -
 	// similar to aliases but only suggests.
 	SuggestFor []string
@@ -55,12 +126,32 @@ type Command struct {
 	// Example is examples of how to use the command.
 	Example string
 
+	// LongFunc, if set, returns the long message in place of Long. It is only called
+	// when the help or the documentation of the command is rendered, so that long
+	// messages which are expensive to build, e.g. listing the installed plugins, do
+	// not slow down the other invocations. Custom templates must use .LongText for it
+	// to be called.
+	LongFunc func(cmd *Command) string
+
+	// ExampleFunc, if set, returns the examples in place of Example. Like LongFunc, it
+	// is only called when the help or the documentation of the command is rendered.
+	// Custom templates must use .ExampleText for it to be called.
+	ExampleFunc func(cmd *Command) string
+
 	// ValidArgs is list of all valid non-flag arguments that are accepted in bash completions
 	ValidArgs []string
 	// ValidArgsFunction is an optional function that provides valid non-flag arguments for bash completion.
 	// It is a dynamic version of using ValidArgs.
 	// Only one of ValidArgs and ValidArgsFunction can be used for a command.
 	ValidArgsFunction func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective)
+	// PersistentValidArgsFunction is like ValidArgsFunction, but also provides the valid
+	// non-flag arguments of the descendants of the command which set neither ValidArgs
+	// nor ValidArgsFunction, e.g. when they all take the name of a resource.
+	PersistentValidArgsFunction func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective)
+
+	// CompletionOptions is a set of options to control the handling of shell completion.
+	// It is only read from the root command.
+	CompletionOptions CompletionOptions
 
 	// Expected arguments
 	Args PositionalArgs
@@ -136,6 +227,11 @@ type Command struct {
 	// line of a command when printing help or generating docs
 	DisableFlagsInUseLine bool
 
+	// RequiredFlagsInUseLine will enable the addition of the required flags,
+	// e.g. "--name <value>", to the usage line of the command and of its
+	// descendants when printing help or generating docs
+	RequiredFlagsInUseLine bool
+
 	// DisableSuggestions disables the suggestions based on Levenshtein distance
 	// that go along with 'unknown command' messages.
 	DisableSuggestions bool
@@ -149,10 +245,34 @@ type Command struct {
 	// FParseErrWhitelist flag parse errors to be ignored
 	FParseErrWhitelist FParseErrWhitelist
 
+	// StrictFlags rejects any argument before "--" starting with "-" which is not a
+	// defined flag, ignoring FParseErrWhitelist, including the values of flags which
+	// look like flags, e.g. "--typo" in "--name --typo"; such values must be given
+	// as "--name=--typo". Negative numbers, e.g. "--offset -5", are accepted as values.
+	// It hardens sensitive commands in an otherwise lenient tree.
+	StrictFlags bool
+
+	// RunOnce makes any attempt to run the command after its first run fail with an
+	// error instead of running it again, for the commands mutating the global state of
+	// the process, e.g. in a REPL. The attempts not reaching the run functions, such as
+	// requesting the help or failing the validation of the arguments and flags or a
+	// pre-run function, do not count. The guard is kept by ResetFlags: run a new
+	// instance of the command to run it again.
+	RunOnce bool
+
 	ctx context.Context
+	// hasRun is set once the run functions of the command are called, for RunOnce.
+	hasRun bool
 
 	// commands is the list of commands supported by this program.
 	commands []*Command
+	// commandgroups are the groups of the subcommands, added with AddGroup.
+	commandgroups []*Group
+	// flagGroups are the names of the groups of the local flags, added with AddFlagGroup.
+	flagGroups []string
+	// missingRequiredFlags are the names of the flags marked as required which do not
+	// exist, reported by ValidateTree.
+	missingRequiredFlags []string
 	// parent is a parent command for this command.
 	parent *Command
 	// Max lengths of commands' string lengths for use in padding.
@@ -161,11 +281,15 @@ type Command struct {
 	commandsMaxNameLen        int
 	// commandsAreSorted defines, if command slice are sorted or not.
 	commandsAreSorted bool
+	// commandSort orders the child commands, overriding EnableCommandSorting.
+	commandSort func(cmds []*Command) []*Command
 	// commandCalledAs is the name or alias value used to call this command.
 	commandCalledAs struct {
 		name   string
 		called bool
 	}
+	// executeMu serializes the calls to ExecuteArgs on the tree of the command.
+	executeMu sync.Mutex
 	// related commands is the list of commands related to this command.
 	relatedCommands []*Command
 
@@ -198,9 +322,28 @@ type Command struct {
 	helpTemplate string
 	// helpFunc is help func defined by user.
 	helpFunc func(*Command, []string)
+	// validateFunc is the post-parse validation func defined by user.
+	validateFunc func(*Command) error
+	// emptyInvocationRunE is run in place of Run when the command is invoked without arguments.
+	emptyInvocationRunE func(cmd *Command, args []string) error
+	// subcommandDescriptionFunc returns the descriptions of the listed subcommands.
+	subcommandDescriptionFunc func(*Command) string
+	// completionResultHook post-processes the completion choices of the command and its descendants.
+	completionResultHook func(cmd *Command, comps []string, directive ShellCompDirective) ([]string, ShellCompDirective)
+	// exclusiveCommandGroups are the groups of child commands of which only one can be used.
+	exclusiveCommandGroups [][]*Command
+	// deprecatedAliases maps the deprecated aliases of the command to their deprecation message.
+	deprecatedAliases map[string]string
+	// rawUseLine makes UseLine return Use unmodified, after the path of the parent.
+	rawUseLine bool
+	// unknownCommandHandler handles the invocations of unknown subcommands.
+	unknownCommandHandler func(cmd *Command, args []string) error
 	// helpCommand is command with usage 'help'. If it's not defined by user,
 	// cobra uses default help command.
 	helpCommand *Command
+	// versionCommand is command with usage 'version', added by InitDefaultVersionCmd.
+	// If it's not defined by user, cobra uses default version command.
+	versionCommand *Command
 	// versionTemplate is the version template defined by user.
 	versionTemplate string
 
@@ -224,6 +367,19 @@ func (c *Command) SetArgs(a []string) {
 	c.args = a
 }
 
+// SetArgsString splits line into arguments the way a POSIX shell would,
+// honoring single quotes, double quotes and backslash escapes, and passes
+// them to SetArgs. It returns an error if a quote is left unbalanced or the
+// line ends with a dangling escape.
+func (c *Command) SetArgsString(line string) error {
+	args, err := shellSplit(line)
+	if err != nil {
+		return err
+	}
+	c.SetArgs(args)
+	return nil
+}
+
 // SetOutput sets the destination for usage and error messages.
 // If output is nil, os.Stderr is used.
 // Deprecated: Use SetOut and/or SetErr instead
@@ -271,11 +427,112 @@ func (c *Command) SetHelpFunc(f func(*Command, []string)) {
 	c.helpFunc = f
 }
 
+// SetValidate sets a function validating the command once its flags are parsed,
+// run after the required flags are checked and before Run. It is the place to
+// check constraints between flags; to report all the problems at once, return
+// them joined into a single error, e.g. with their messages on separate lines.
+// Returned errors are usage errors.
+func (c *Command) SetValidate(f func(*Command) error) {
+	c.validateFunc = f
+}
+
+// SetSubcommandDescriptionFunc sets a function returning the description of each
+// subcommand listed in the "Available Commands" of the help of c and its descendants,
+// in place of its Short, e.g. to append a tag to it. It receives the subcommand.
+func (c *Command) SetSubcommandDescriptionFunc(f func(*Command) string) {
+	c.subcommandDescriptionFunc = f
+}
+
+// SubcommandDescription returns the description of c listed in the help of its parent:
+// the result of the function set with SetSubcommandDescriptionFunc on its parent, or
+// on their nearest ancestor having one, or Short.
+func (c *Command) SubcommandDescription() string {
+	for p := c.Parent(); p != nil; p = p.Parent() {
+		if p.subcommandDescriptionFunc != nil {
+			return p.subcommandDescriptionFunc(c)
+		}
+	}
+	return c.Short
+}
+
+// SetEmptyInvocationRunE sets a function run in place of Run when the command
+// is invoked without any argument nor flag, e.g. when the bare binary is run for
+// the root command. It allows running a default action instead of printing the
+// help of a command which is not runnable. The hooks of the command are run
+// around it as for Run. If unset, the regular behavior applies.
+func (c *Command) SetEmptyInvocationRunE(f func(cmd *Command, args []string) error) {
+	c.emptyInvocationRunE = f
+}
+
+// SetUnknownCommandHandler sets a function called when the name of an unknown
+// subcommand of c, or of one of its descendants which has no handler of its own,
+// is given on the command-line, before the "unknown command" error is reported.
+// It allows dispatching to external commands, e.g. running "app-<name>" from the
+// PATH for plugins. The function receives the command whose subcommand is unknown,
+// and the remaining arguments starting with the unknown name. Returning nil means
+// the invocation was handled; returning ErrUnknownCommand falls through to the
+// regular "unknown command" error, with its suggestions; any other error is
+// reported and returned by Execute, like the errors of the run functions.
+func (c *Command) SetUnknownCommandHandler(f func(cmd *Command, args []string) error) {
+	c.unknownCommandHandler = f
+}
+
+// ErrUnknownCommand is returned by the functions set with SetUnknownCommandHandler
+// which do not handle the unknown subcommand they are given.
+var ErrUnknownCommand = errors.New("unknown command")
+
+// handleUnknownCommand calls the unknown command handler of cmd or of its nearest
+// parent for the remaining args. It returns true if the handler handled them, along
+// with the error the handler returned.
+func (c *Command) handleUnknownCommand(args []string) (bool, error) {
+	argsWOflags := stripFlags(args, c)
+	if len(argsWOflags) == 0 {
+		return false, nil
+	}
+	name := argsWOflags[0]
+	for p := c; p != nil; p = p.Parent() {
+		if p.unknownCommandHandler != nil {
+			err := p.unknownCommandHandler(c, append([]string{name}, argsMinusFirstX(args, name)...))
+			if err == ErrUnknownCommand {
+				return false, nil
+			}
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// SetRawUseLine makes the usage line of the command its Use field verbatim,
+// preceded by the path of its parent, for commands whose Use describes their
+// complete synopsis: neither "[flags]" nor the required flags are added to it.
+func (c *Command) SetRawUseLine(raw bool) {
+	c.rawUseLine = raw
+}
+
 // SetHelpCommand sets help command.
 func (c *Command) SetHelpCommand(cmd *Command) {
 	c.helpCommand = cmd
 }
 
+// SetVersionCommand sets the version command added by InitDefaultVersionCmd.
+func (c *Command) SetVersionCommand(cmd *Command) {
+	c.versionCommand = cmd
+}
+
+// SetCommandSort sets a function ordering the child commands of c, as returned by
+// Commands and listed in its help and docs, e.g. in lifecycle order. It receives
+// the child commands in the order they were added, and overrides
+// EnableCommandSorting for c; it does not apply to the descendants of c.
+func (c *Command) SetCommandSort(f func(cmds []*Command) []*Command) {
+	c.commandSort = f
+}
+
+// HasCommandSort returns true if the order of the child commands of c is set with
+// SetCommandSort.
+func (c *Command) HasCommandSort() bool {
+	return c.commandSort != nil
+}
+
 // SetHelpTemplate sets help template to be used. Application can use it to set custom template.
 func (c *Command) SetHelpTemplate(s string) {
 	c.helpTemplate = s
@@ -357,6 +614,21 @@ func (c *Command) UsageFunc() (f func(*Command) error) {
 	if c.HasParent() {
 		return c.Parent().UsageFunc()
 	}
+	return c.DefaultUsageFunc()
+}
+
+// DefaultUsageFunc returns the function rendering the usage template, used when
+// no usage function is set with SetUsageFunc on the command or its ancestors.
+// A custom usage function can call it to decorate the default usage:
+//
+//	rootCmd.SetUsageFunc(func(c *cobra.Command) error {
+//		if err := c.DefaultUsageFunc()(c); err != nil {
+//			return err
+//		}
+//		c.PrintErrln("Documentation: https://example.com/docs")
+//		return nil
+//	})
+func (c *Command) DefaultUsageFunc() func(*Command) error {
 	return func(c *Command) error {
 		c.mergePersistentFlags()
 		err := tmpl(c.OutOrStderr(), c.UsageTemplate(), c)
@@ -421,6 +693,24 @@ func (c *Command) UsageString() string {
 	return bb.String()
 }
 
+// HelpString returns the help of the command, as printed by Help, including what the
+// help function prints to stderr.
+func (c *Command) HelpString() string {
+	tmpOutput := c.outWriter
+	tmpErr := c.errWriter
+
+	bb := new(bytes.Buffer)
+	c.outWriter = bb
+	c.errWriter = bb
+
+	c.Help()
+
+	c.outWriter = tmpOutput
+	c.errWriter = tmpErr
+
+	return bb.String()
+}
+
 // FlagErrorFunc returns either the function set by SetFlagErrorFunc for this
 // command or a parent, or it returns a function which returns the original
 // error.
@@ -481,19 +771,31 @@ func (c *Command) UsageTemplate() string {
   {{.CommandPath}} [command]{{end}}{{if gt (len .Aliases) 0}}
 
 Aliases:
-  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+  {{.NameAndAliases}}{{end}}{{with .ExampleText}}
 
 Examples:
-{{.Example}}{{end}}{{if .HasAvailableSubCommands}}
+{{.}}{{end}}{{if .HasAvailableSubCommands}}
+
+{{$cmds := .Commands}}{{if eq (len .Groups) 0}}Available Commands:{{range $cmds}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.SubcommandDescription}}{{end}}{{end}}{{else}}{{range $i, $group := .Groups}}{{if $i}}
 
-Available Commands:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
-  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+{{end}}{{.Title}}{{range $cmds}}{{if (and (eq .GroupID $group.ID) (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding }} {{.SubcommandDescription}}{{end}}{{end}}{{end}}{{if not .AllChildCommandsHaveGroup}}
+
+Additional Commands:{{range $cmds}}{{if (and (eq .GroupID "") (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding }} {{.SubcommandDescription}}{{end}}{{end}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}{{if eq (len .FlagGroups) 0}}
 
 Flags:
-{{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+{{flagUsages .LocalFlags | trimTrailingWhitespaces}}{{else}}{{$flags := .LocalFlagsInGroup ""}}{{if $flags.HasAvailableFlags}}
+
+Flags:
+{{flagUsages $flags | trimTrailingWhitespaces}}{{end}}{{range $group := .FlagGroups}}{{$flags := $.LocalFlagsInGroup $group}}{{if $flags.HasAvailableFlags}}
+
+{{$group}} Flags:
+{{flagUsages $flags | trimTrailingWhitespaces}}{{end}}{{end}}{{end}}{{end}}{{if .HasAvailableInheritedFlags}}
 
 Global Flags:
-{{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+{{flagUsages .InheritedFlags | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
 
 Additional help topics:{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
   {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
@@ -511,7 +813,7 @@ func (c *Command) HelpTemplate() string {
 	if c.HasParent() {
 		return c.parent.HelpTemplate()
 	}
-	return `{{with (or .Long .Short)}}{{. | trimTrailingWhitespaces}}
+	return `{{with (or .LongText .Short)}}{{. | trimTrailingWhitespaces}}
 
 {{end}}{{if or .Runnable .HasSubCommands}}{{.UsageString}}{{end}}`
 }
@@ -645,6 +947,14 @@ func (c *Command) findSuggestions(arg string) string {
 		for _, s := range suggestions {
 			suggestionsString += fmt.Sprintf("\t%v\n", s)
 		}
+		for _, group := range c.exclusiveCommandGroups {
+			for _, cmd := range group {
+				if stringInSlice(cmd.Name(), suggestions) {
+					suggestionsString += fmt.Sprintf("\nOnly one of these commands can be used: %s\n", commandNames(group))
+					break
+				}
+			}
+		}
 	}
 	return suggestionsString
 }
@@ -762,6 +1072,9 @@ func (c *Command) execute(a []string) (err error) {
 	if len(c.Deprecated) > 0 {
 		c.Printf("Command %q is deprecated, %s\n", c.Name(), c.Deprecated)
 	}
+	if message, ok := c.AliasDeprecation(c.CalledAs()); ok {
+		fmt.Fprintf(c.ErrOrStderr(), "Alias %q is deprecated, %s\n", c.CalledAs(), message)
+	}
 
 	// initialize help and version flag at the last point possible to allow for user
 	// overriding
@@ -770,7 +1083,7 @@ func (c *Command) execute(a []string) (err error) {
 
 	err = c.ParseFlags(a)
 	if err != nil {
-		return c.FlagErrorFunc()(c, err)
+		return newUsageError(c.FlagErrorFunc()(c, err))
 	}
 
 	// If help is called, regardless of other flags, return we want help.
@@ -803,10 +1116,18 @@ func (c *Command) execute(a []string) (err error) {
 		}
 	}
 
-	if !c.Runnable() {
+	emptyInvocation := c.emptyInvocationRunE != nil && len(a) == 0
+	if !c.Runnable() && !emptyInvocation {
 		return flag.ErrHelp
 	}
 
+	if err := c.applyFlagDependencies(); err != nil {
+		return newUsageError(err)
+	}
+	if err := c.validateFlagGroups(); err != nil {
+		return newUsageError(err)
+	}
+
 	c.preRun()
 
 	argWoFlags := c.Flags().Args()
@@ -814,8 +1135,14 @@ func (c *Command) execute(a []string) (err error) {
 		argWoFlags = a
 	}
 
-	if err := c.ValidateArgs(argWoFlags); err != nil {
-		return err
+	if !emptyInvocation {
+		if err := c.ValidateArgs(argWoFlags); err != nil {
+			return newUsageError(err)
+		}
+	}
+
+	if c.RunOnce && c.hasRun {
+		return fmt.Errorf("command %q has already run and cannot run again", c.CommandPath())
 	}
 
 	for p := c; p != nil; p = p.Parent() {
@@ -837,10 +1164,26 @@ func (c *Command) execute(a []string) (err error) {
 		c.PreRun(c, argWoFlags)
 	}
 
+	if c.debugFlagsRequested() {
+		return c.printFlagSources()
+	}
+
 	if err := c.validateRequiredFlags(); err != nil {
-		return err
+		return newUsageError(err)
 	}
-	if c.RunE != nil {
+	if c.validateFunc != nil {
+		if err := c.validateFunc(c); err != nil {
+			return newUsageError(err)
+		}
+	}
+	// Only an invocation reaching the run functions counts as a run, so that it can be
+	// retried after a failed validation.
+	c.hasRun = true
+	if emptyInvocation {
+		if err := c.emptyInvocationRunE(c, argWoFlags); err != nil {
+			return err
+		}
+	} else if c.RunE != nil {
 		if err := c.RunE(c, argWoFlags); err != nil {
 			return err
 		}
@@ -882,6 +1225,289 @@ func (c *Command) ExecuteContext(ctx context.Context) error {
 	return c.Execute()
 }
 
+// ExecuteArgs executes the tree of the command with the given arguments and context,
+// like ExecuteContext, without reading os.Args nor changing the arguments set with
+// SetArgs. It is meant for embedding, e.g. in a server handling many invocations:
+// the contexts, the called names and the flags of the commands are restored after the
+// execution to what they were before it, including the values set in code, so that
+// an execution does not see the state of a previous one. The concurrent calls on the
+// same tree are serialized, as the commands hold that state.
+// A command can run the tree again, e.g. a REPL reading invocations, by calling
+// ExecuteArgs with a context derived from its own, cmd.Context(); with an unrelated
+// context, such a nested call would wait for the running execution forever.
+// With versions of pflag whose slice values cannot be replaced, the slice flags keep
+// the values they were set to.
+func (c *Command) ExecuteArgs(ctx context.Context, args []string) error {
+	root := c.Root()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ctx.Value(executeArgsKey{}) != root {
+		root.executeMu.Lock()
+		defer root.executeMu.Unlock()
+		ctx = context.WithValue(ctx, executeArgsKey{}, root)
+	}
+
+	type commandState struct {
+		ctx      context.Context
+		calledAs string
+		called   bool
+	}
+	states := map[*Command]commandState{}
+	var save func(*Command)
+	save = func(cmd *Command) {
+		states[cmd] = commandState{cmd.ctx, cmd.commandCalledAs.name, cmd.commandCalledAs.called}
+		for _, child := range cmd.commands {
+			save(child)
+		}
+	}
+	save(root)
+	restoreFlags := saveFlags(root)
+	savedArgs := root.args
+	defer func() {
+		root.args = savedArgs
+		for cmd, state := range states {
+			cmd.ctx = state.ctx
+			cmd.commandCalledAs.name = state.calledAs
+			cmd.commandCalledAs.called = state.called
+		}
+		restoreFlags()
+	}()
+
+	if args == nil {
+		// A nil slice would make Execute read os.Args.
+		args = []string{}
+	}
+	root.args = args
+	root.ctx = ctx
+	_, err := root.ExecuteC()
+	return err
+}
+
+// CheckInvocation checks that args invoke a command of the tree of c as Execute would
+// accept them, without running anything, e.g. to test the examples of the commands. It
+// finds the command, parses its flags and validates them and the arguments: the flag
+// groups and dependencies, the positional arguments, the required flags and the function
+// set with SetValidate. The flags of the tree are restored afterwards, so that the checks
+// do not see each other's values. It returns the command found, if any.
+func (c *Command) CheckInvocation(args []string) (*Command, error) {
+	root := c.Root()
+	restoreFlags := saveFlags(root)
+	defer restoreFlags()
+	restoreSliceFlags := isolateSliceFlags(root)
+	defer restoreSliceFlags()
+
+	var cmd *Command
+	var flags []string
+	var err error
+	if root.TraverseChildren {
+		cmd, flags, err = root.Traverse(args)
+	} else {
+		cmd, flags, err = root.Find(args)
+	}
+	if err != nil {
+		return cmd, err
+	}
+
+	cmd.InitDefaultHelpFlag()
+	cmd.InitDefaultVersionFlag()
+	if err := cmd.ParseFlags(flags); err != nil {
+		return cmd, err
+	}
+	if helpVal, err := cmd.Flags().GetBool("help"); err == nil && helpVal {
+		return cmd, nil
+	}
+	if err := cmd.applyFlagDependencies(); err != nil {
+		return cmd, err
+	}
+	if err := cmd.validateFlagGroups(); err != nil {
+		return cmd, err
+	}
+	argWoFlags := cmd.Flags().Args()
+	if cmd.DisableFlagParsing {
+		argWoFlags = flags
+	}
+	if err := cmd.ValidateArgs(argWoFlags); err != nil {
+		return cmd, err
+	}
+	if err := cmd.validateRequiredFlags(); err != nil {
+		return cmd, err
+	}
+	if cmd.validateFunc != nil {
+		return cmd, cmd.validateFunc(cmd)
+	}
+	return cmd, nil
+}
+
+// executeArgsKey is the key of the context value marking the executions of
+// ExecuteArgs, holding the root of the executed tree.
+type executeArgsKey struct{}
+
+// saveFlags saves the state of the flags of the tree of root, and returns the function
+// restoring it.
+func saveFlags(root *Command) (restore func()) {
+	flags := map[*flag.Flag]flagState{}
+	saveFlag := func(f *flag.Flag) {
+		if _, ok := flags[f]; !ok {
+			flags[f] = newFlagState(f)
+		}
+	}
+	var save func(*Command)
+	save = func(cmd *Command) {
+		cmd.Flags().VisitAll(saveFlag)
+		cmd.PersistentFlags().VisitAll(saveFlag)
+		for _, child := range cmd.commands {
+			save(child)
+		}
+	}
+	save(root)
+	return func() {
+		for f, state := range flags {
+			state.restore(f)
+		}
+	}
+}
+
+// isolateSliceFlags replaces the values of the slice flags of the tree of root by new
+// values holding their defaults, and returns the function putting the original values
+// back. Unlike the other values, those of pflag cannot be restored by saveFlags once
+// set, as they append the values set after the first one.
+func isolateSliceFlags(root *Command) (restore func()) {
+	values := map[*flag.Flag]flag.Value{}
+	isolate := func(f *flag.Flag) {
+		if _, ok := values[f]; ok {
+			return
+		}
+		if value := newSliceValue(f); value != nil {
+			values[f] = f.Value
+			f.Value = value
+		}
+	}
+	var visit func(*Command)
+	visit = func(cmd *Command) {
+		cmd.Flags().VisitAll(isolate)
+		cmd.PersistentFlags().VisitAll(isolate)
+		for _, child := range cmd.commands {
+			visit(child)
+		}
+	}
+	visit(root)
+	return func() {
+		for f, value := range values {
+			f.Value = value
+		}
+	}
+}
+
+// newSliceValue returns a new value of the type of the slice flag f holding its default
+// value, or nil if f is not a slice flag of pflag or its default cannot be parsed.
+func newSliceValue(f *flag.Flag) flag.Value {
+	var values []string
+	if def := strings.TrimSuffix(strings.TrimPrefix(f.DefValue, "["), "]"); def != "" {
+		var err error
+		if values, err = csv.NewReader(strings.NewReader(def)).Read(); err != nil {
+			return nil
+		}
+	}
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	switch f.Value.Type() {
+	case "stringSlice":
+		fs.StringSlice("value", values, "")
+	case "stringArray":
+		fs.StringArray("value", values, "")
+	case "intSlice":
+		ints := make([]int, len(values))
+		for i, v := range values {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil
+			}
+			ints[i] = n
+		}
+		fs.IntSlice("value", ints, "")
+	case "uintSlice":
+		uints := make([]uint, len(values))
+		for i, v := range values {
+			n, err := strconv.ParseUint(v, 10, 0)
+			if err != nil {
+				return nil
+			}
+			uints[i] = uint(n)
+		}
+		fs.UintSlice("value", uints, "")
+	case "boolSlice":
+		bools := make([]bool, len(values))
+		for i, v := range values {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil
+			}
+			bools[i] = b
+		}
+		fs.BoolSlice("value", bools, "")
+	case "durationSlice":
+		durations := make([]time.Duration, len(values))
+		for i, v := range values {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil
+			}
+			durations[i] = d
+		}
+		fs.DurationSlice("value", durations, "")
+	case "ipSlice":
+		ips := make([]net.IP, len(values))
+		for i, v := range values {
+			if ips[i] = net.ParseIP(v); ips[i] == nil {
+				return nil
+			}
+		}
+		fs.IPSlice("value", ips, "")
+	default:
+		return nil
+	}
+	return fs.Lookup("value").Value
+}
+
+// flagState is the state of a flag saved by saveFlags.
+type flagState struct {
+	value   string
+	changed bool
+	source  []string
+}
+
+func newFlagState(f *flag.Flag) flagState {
+	return flagState{
+		value:   f.Value.String(),
+		changed: f.Changed,
+		source:  f.Annotations[FlagSourceAnnotation],
+	}
+}
+
+// restore sets the flag back to the saved state.
+func (s flagState) restore(f *flag.Flag) {
+	if f.Value.String() != s.value {
+		if slice, ok := f.Value.(interface{ Replace([]string) error }); ok {
+			var values []string
+			if saved := strings.TrimSuffix(strings.TrimPrefix(s.value, "["), "]"); saved != "" {
+				values, _ = csv.NewReader(strings.NewReader(saved)).Read()
+			}
+			_ = slice.Replace(values)
+		} else if typ := f.Value.Type(); !strings.HasSuffix(typ, "Slice") && !strings.HasSuffix(typ, "Array") {
+			_ = f.Value.Set(s.value)
+		}
+	}
+	f.Changed = s.changed
+	if s.source != nil {
+		if f.Annotations == nil {
+			f.Annotations = map[string][]string{}
+		}
+		f.Annotations[FlagSourceAnnotation] = s.source
+	} else {
+		delete(f.Annotations, FlagSourceAnnotation)
+	}
+}
+
 // Execute uses the args (os.Args[1:] by default)
 // and run through the command tree finding appropriate matches
 // for commands and then corresponding flags.
@@ -910,6 +1536,9 @@ func (c *Command) ExecuteC() (cmd *Command, err error) {
 	// overriding
 	c.InitDefaultHelpCmd()
 
+	// Check if there are checks to be done on the command groups
+	c.checkCommandGroups()
+
 	args := c.args
 
 	// Workaround FAIL with "go test -v" or "cobra.test -test.v", see #155
@@ -926,14 +1555,32 @@ func (c *Command) ExecuteC() (cmd *Command, err error) {
 	} else {
 		cmd, flags, err = c.Find(args)
 	}
+	if err != nil && cmd != nil {
+		if handled, handlerErr := cmd.handleUnknownCommand(flags); handled {
+			if handlerErr != nil && !cmd.SilenceErrors && !c.SilenceErrors {
+				if cmd.errorFormat(args) == ErrorFormatJSON {
+					c.printJSONError(cmd, handlerErr)
+				} else {
+					c.Println("Error:", handlerErr.Error())
+				}
+			}
+			return cmd, handlerErr
+		}
+	}
 	if err != nil {
+		err = newUsageError(err)
 		// If found parse to a subcommand and then failed, talk about the subcommand
 		if cmd != nil {
 			c = cmd
 		}
+		err = c.redactSensitiveValues(err, args)
 		if !c.SilenceErrors {
-			c.Println("Error:", err.Error())
-			c.Printf("Run '%v --help' for usage.\n", c.CommandPath())
+			if c.errorFormat(args) == ErrorFormatJSON {
+				c.printJSONError(c, err)
+			} else {
+				c.Println("Error:", err.Error())
+				c.Printf("Run '%v --help' for usage.\n", c.CommandPath())
+			}
 		}
 		return c, err
 	}
@@ -950,6 +1597,7 @@ func (c *Command) ExecuteC() (cmd *Command, err error) {
 	}
 
 	err = cmd.execute(flags)
+	err = cmd.redactSensitiveValues(err, flags)
 	if err != nil {
 		// Always show help if requested, even if SilenceErrors is in
 		// effect
@@ -958,6 +1606,13 @@ func (c *Command) ExecuteC() (cmd *Command, err error) {
 			return cmd, nil
 		}
 
+		if cmd.errorFormat(args) == ErrorFormatJSON {
+			if !cmd.SilenceErrors && !c.SilenceErrors {
+				c.printJSONError(cmd, err)
+			}
+			return cmd, err
+		}
+
 		// If root command has SilentErrors flagged,
 		// all subcommands should respect it
 		if !cmd.SilenceErrors && !c.SilenceErrors {
@@ -1071,11 +1726,44 @@ Simply type ` + c.Name() + ` help [path to command] for full details.`,
 	c.AddCommand(c.helpCommand)
 }
 
-// ResetCommands delete parent, subcommand and help command from c.
+// InitDefaultVersionCmd adds default version command to c, printing the version of c
+// with its version template, like the version flag. Unlike the help command, it is not
+// added automatically: call it on the root command before executing it.
+// If c has no Version or already has a version subcommand, it will do nothing.
+func (c *Command) InitDefaultVersionCmd() {
+	if c.Version == "" {
+		return
+	}
+	for _, cmd := range c.commands {
+		if cmd.Name() == "version" && cmd != c.versionCommand {
+			return
+		}
+	}
+
+	if c.versionCommand == nil {
+		name := c.Name()
+		if name == "" {
+			name = "this command"
+		}
+		c.versionCommand = &Command{
+			Use:   "version",
+			Short: "Print the version of " + name,
+			Args:  NoArgs,
+			RunE: func(cmd *Command, args []string) error {
+				return tmpl(cmd.OutOrStdout(), c.VersionTemplate(), c)
+			},
+		}
+	}
+	c.RemoveCommand(c.versionCommand)
+	c.AddCommand(c.versionCommand)
+}
+
+// ResetCommands delete parent, subcommand, help and version commands from c.
 func (c *Command) ResetCommands() {
 	c.parent = nil
 	c.commands = nil
 	c.helpCommand = nil
+	c.versionCommand = nil
 	c.parentsPflags = nil
 }
 
@@ -1088,6 +1776,11 @@ func (c commandSorterByName) Less(i, j int) bool { return c[i].Name() < c[j].Nam
 
 // Commands returns a sorted slice of child commands.
 func (c *Command) Commands() []*Command {
+	if c.commandSort != nil {
+		cmds := make([]*Command, len(c.commands))
+		copy(cmds, c.commands)
+		return c.commandSort(cmds)
+	}
 	// do not sort commands if it already sorted or sorting was disabled
 	if EnableCommandSorting && !c.commandsAreSorted {
 		sort.Sort(commandSorterByName(c.commands))
@@ -1125,6 +1818,298 @@ func (c *Command) AddCommand(cmds ...*Command) {
 	}
 }
 
+// ValidateTree walks the command tree starting at c and reports structural
+// problems which would otherwise only surface when the affected command is used:
+// sibling commands sharing a name or alias, commands in a group which was not
+// added to their parent with AddGroup, flags marked as required with
+// MarkFlagRequired or MarkPersistentFlagRequired which do not exist, commands
+// setting both ValidArgs and ValidArgsFunction, and flag completion functions
+// registered for flags which are no longer part of their command.
+// It returns nil if no problem was found. It is meant to be called from a test.
+func (c *Command) ValidateTree() error {
+	var problems []string
+	var validate func(*Command)
+	validate = func(cmd *Command) {
+		if _, completionFn := cmd.argsCompletion(); len(cmd.ValidArgs) > 0 && completionFn != nil {
+			problems = append(problems, fmt.Sprintf("command %q sets both ValidArgs and ValidArgsFunction", cmd.CommandPath()))
+		}
+
+		for _, name := range cmd.missingRequiredFlags {
+			problems = append(problems, fmt.Sprintf("command %q marks missing flag %q as required", cmd.CommandPath(), name))
+		}
+
+		for _, sub := range cmd.commands {
+			if sub.GroupID != "" && !cmd.ContainsGroup(sub.GroupID) {
+				problems = append(problems, fmt.Sprintf("command %q is in group %q, which is not added to %q",
+					sub.CommandPath(), sub.GroupID, cmd.CommandPath()))
+			}
+		}
+
+		names := map[string]*Command{}
+		for _, sub := range cmd.commands {
+			for _, name := range append([]string{sub.Name()}, sub.Aliases...) {
+				if other, ok := names[name]; ok && other != sub {
+					problems = append(problems, fmt.Sprintf("commands %q and %q both use the name or alias %q",
+						other.CommandPath(), sub.CommandPath(), name))
+					continue
+				}
+				names[name] = sub
+			}
+		}
+
+		for _, sub := range cmd.commands {
+			validate(sub)
+		}
+	}
+	validate(c)
+
+	var missingFlags []string
+	for flag, cmd := range flagCompletionCommands {
+		if cmd.Root() != c.Root() || cmd.Flag(flag.Name) == flag {
+			continue
+		}
+		missingFlags = append(missingFlags, fmt.Sprintf("command %q has a completion function registered for missing flag %q",
+			cmd.CommandPath(), flag.Name))
+	}
+	sort.Strings(missingFlags)
+	problems = append(problems, missingFlags...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid command tree:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Example is an example of a command, as returned by StructuredExamples.
+type Example struct {
+	// Description tells what the example does, from the comment lines preceding it.
+	Description string `json:"description,omitempty"`
+	// Command is the command line of the example, without prompt.
+	Command string `json:"command"`
+}
+
+// StructuredExamples parses the Example of the command into its examples. Each line
+// which is not a comment is the command line of an example, and the comment lines,
+// starting with "#", right before it are its description; blank lines separate the
+// examples. The command lines may begin with a "$ " prompt, which is removed, and
+// those ending with a backslash are continued on the next line.
+func (c *Command) StructuredExamples() []Example {
+	lines := strings.Split(c.ExampleText(), "\n")
+	var examples []Example
+	var description []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case len(line) == 0:
+			description = nil
+		case strings.HasPrefix(line, "#"):
+			description = append(description, strings.TrimSpace(strings.TrimLeft(line, "#")))
+		default:
+			command := strings.TrimSpace(strings.TrimPrefix(line, "$ "))
+			for strings.HasSuffix(command, "\\") && i+1 < len(lines) {
+				i++
+				command = strings.TrimSpace(strings.TrimSuffix(command, "\\")) + " " + strings.TrimSpace(lines[i])
+			}
+			examples = append(examples, Example{Description: strings.Join(description, " "), Command: command})
+			description = nil
+		}
+	}
+	return examples
+}
+
+// ExamplesMatchCompletion checks that the positional arguments used in the Example
+// of the command are offered by its completion: they must be part of ValidArgs, or
+// of the choices returned by ValidArgsFunction, or by the PersistentValidArgsFunction
+// it inherits. The examples, as returned by
+// StructuredExamples, invoking the command are recognized by its full path.
+// Arguments for which the function returns no choice are not checked, as their
+// completion is not static. It returns one error per mismatch, and is meant to be
+// called from a test.
+func (c *Command) ExamplesMatchCompletion() []error {
+	var errs []error
+	_, completionFn := c.argsCompletion()
+	if len(c.ValidArgs) == 0 && completionFn == nil {
+		return nil
+	}
+	path := strings.Fields(c.CommandPath())
+	for _, example := range c.StructuredExamples() {
+		line := example.Command
+		words, err := shellSplit(line)
+		if err != nil {
+			continue
+		}
+		start := wordsIndex(words, path)
+		if start < 0 {
+			continue
+		}
+
+		args := examplePositionalArgs(c, words[start+len(path):])
+		for i, arg := range args {
+			var choices []string
+			if len(c.ValidArgs) > 0 {
+				choices = c.ValidArgs
+			} else {
+				var directive ShellCompDirective
+				choices, directive = completionFn(c, args[:i], "")
+				if directive&ShellCompDirectiveError != 0 || len(choices) == 0 {
+					continue
+				}
+			}
+			if !isCompletionChoice(arg, choices) {
+				errs = append(errs, fmt.Errorf("example %q of %q: argument %q is not offered by completion",
+					line, c.CommandPath(), arg))
+			}
+		}
+	}
+	return errs
+}
+
+// ExampleInvocations returns the arguments, as passed to the root command, of the
+// examples of the command, as returned by StructuredExamples, which invoke it. Like for
+// ExamplesMatchCompletion, these examples are recognized by the full path of the
+// command; the arguments stop at a comment or at a shell operator, such as a pipe.
+func (c *Command) ExampleInvocations() [][]string {
+	var invocations [][]string
+	path := strings.Fields(c.CommandPath())
+	for _, example := range c.StructuredExamples() {
+		line := example.Command
+		words, err := shellSplit(line)
+		if err != nil {
+			continue
+		}
+		start := wordsIndex(words, path)
+		if start < 0 {
+			continue
+		}
+
+		args := []string{}
+		for _, word := range words[start+1:] {
+			if strings.HasPrefix(word, "#") || stringInSlice(word, []string{"|", "||", "&&", ";", ">", ">>", "<"}) {
+				break
+			}
+			args = append(args, word)
+		}
+		invocations = append(invocations, args)
+	}
+	return invocations
+}
+
+// wordsIndex returns the index of the first occurrence of sub in words, or -1.
+func wordsIndex(words, sub []string) int {
+	for i := 0; i+len(sub) <= len(words); i++ {
+		match := true
+		for j, word := range sub {
+			if words[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// examplePositionalArgs returns the positional arguments among the words following
+// the command in an example, skipping the flags of c and their values, up to a comment.
+func examplePositionalArgs(c *Command, words []string) []string {
+	var args []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case strings.HasPrefix(word, "#"):
+			return args
+		case word == "--":
+			return append(args, words[i+1:]...)
+		case len(word) > 1 && word[0] == '-':
+			if strings.Contains(word, "=") {
+				continue
+			}
+			name := strings.TrimLeft(word, "-")
+			if !strings.HasPrefix(word, "--") {
+				// The last shorthand of a group is the one which can take a value.
+				name = name[len(name)-1:]
+			}
+			if f := c.LookupFlag(name); f != nil && len(f.NoOptDefVal) == 0 {
+				i++
+			}
+		default:
+			args = append(args, word)
+		}
+	}
+	return args
+}
+
+// isCompletionChoice returns true if arg is one of the completion choices, which
+// may be followed by a description.
+func isCompletionChoice(arg string, choices []string) bool {
+	for _, choice := range choices {
+		if strings.Split(choice, "\t")[0] == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// AddExclusiveCommandGroup declares that only one of the given child commands can be
+// used at a time, e.g. the "encrypt" and "decrypt" modes of a CLI. The group documents
+// the intent: it is mentioned by the suggestions for unknown commands and by the doc
+// generators, but the arguments of the commands are not checked against it.
+func (c *Command) AddExclusiveCommandGroup(names ...string) error {
+	if len(names) < 2 {
+		return fmt.Errorf("AddExclusiveCommandGroup: a group needs at least 2 commands, got %d", len(names))
+	}
+	group := make([]*Command, 0, len(names))
+	for _, name := range names {
+		var found *Command
+		for _, cmd := range c.commands {
+			if cmd.Name() == name {
+				found = cmd
+				break
+			}
+		}
+		if found == nil {
+			return fmt.Errorf("AddExclusiveCommandGroup: command %q has no subcommand %q", c.CommandPath(), name)
+		}
+		group = append(group, found)
+	}
+	c.exclusiveCommandGroups = append(c.exclusiveCommandGroups, group)
+	return nil
+}
+
+// ExclusiveCommandGroups returns the groups of child commands of which only one can
+// be used at a time, as declared with AddExclusiveCommandGroup.
+func (c *Command) ExclusiveCommandGroups() [][]*Command {
+	return c.exclusiveCommandGroups
+}
+
+// exclusiveGroupsOf returns the exclusive command groups of the parent of c including c.
+func (c *Command) exclusiveGroupsOf() [][]*Command {
+	if !c.HasParent() {
+		return nil
+	}
+	var groups [][]*Command
+	for _, group := range c.Parent().exclusiveCommandGroups {
+		for _, cmd := range group {
+			if cmd == c {
+				groups = append(groups, group)
+				break
+			}
+		}
+	}
+	return groups
+}
+
+// commandNames returns the names of cmds separated by commas.
+func commandNames(cmds []*Command) string {
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
 // RelatedCommands returns a slice of related commands.
 func (c *Command) RelatedCommands() []*Command {
 	return c.relatedCommands
@@ -1140,6 +2125,61 @@ func (c *Command) AddRelatedCommand(cmds ...*Command) {
 	}
 }
 
+// AddCommandInGroup adds one or more commands to this parent command, like AddCommand,
+// and sets their GroupID to groupID, which must have been added with AddGroup.
+// It panics otherwise.
+func (c *Command) AddCommandInGroup(groupID string, cmds ...*Command) {
+	if !c.ContainsGroup(groupID) {
+		panic(fmt.Sprintf("group id '%s' is not defined for command '%s'", groupID, c.CommandPath()))
+	}
+	for _, cmd := range cmds {
+		cmd.GroupID = groupID
+	}
+	c.AddCommand(cmds...)
+}
+
+// Groups returns the groups of the subcommands, in the order they were added.
+func (c *Command) Groups() []*Group {
+	return c.commandgroups
+}
+
+// AllChildCommandsHaveGroup returns true if all the available subcommands, and the
+// help command, are in a group.
+func (c *Command) AllChildCommandsHaveGroup() bool {
+	for _, sub := range c.commands {
+		if (sub.IsAvailableCommand() || sub == c.helpCommand) && sub.GroupID == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsGroup returns true if groupID was added with AddGroup.
+func (c *Command) ContainsGroup(groupID string) bool {
+	for _, group := range c.commandgroups {
+		if group.ID == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// AddGroup adds one or more groups of subcommands, listed in the help in that order.
+func (c *Command) AddGroup(groups ...*Group) {
+	c.commandgroups = append(c.commandgroups, groups...)
+}
+
+// checkCommandGroups panics if a command of the tree of c is in a group which was
+// not added to its parent, so that the developer notices right away.
+func (c *Command) checkCommandGroups() {
+	for _, sub := range c.commands {
+		if sub.GroupID != "" && !c.ContainsGroup(sub.GroupID) {
+			panic(fmt.Sprintf("group id '%s' is not defined for subcommand '%s'", sub.GroupID, sub.CommandPath()))
+		}
+		sub.checkCommandGroups()
+	}
+}
+
 // RemoveCommand removes one or more commands from a parent command.
 func (c *Command) RemoveCommand(cmds ...*Command) {
 	commands := []*Command{}
@@ -1220,15 +2260,141 @@ func (c *Command) UseLine() string {
 	} else {
 		useline = c.Use
 	}
-	if c.DisableFlagsInUseLine {
+	if c.DisableFlagsInUseLine || c.rawUseLine {
 		return useline
 	}
 	if c.HasAvailableFlags() && !strings.Contains(useline, "[flags]") {
 		useline += " [flags]"
 	}
+	if c.hasRequiredFlagsInUseLine() {
+		if required := c.requiredFlagsUsage(); len(required) > 0 {
+			if strings.Contains(useline, "[flags]") {
+				useline = strings.Replace(useline, "[flags]", required+" [flags]", 1)
+			} else {
+				useline += " " + required
+			}
+		}
+	}
 	return useline
 }
 
+// hasRequiredFlagsInUseLine returns true if RequiredFlagsInUseLine is set on c or on
+// one of its ancestors.
+func (c *Command) hasRequiredFlagsInUseLine() bool {
+	for p := c; p != nil; p = p.Parent() {
+		if p.RequiredFlagsInUseLine {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredFlagsUsage returns the usage of the available required flags of the
+// command for the usage line, e.g. "--name <value> --force". The persistent flags
+// of the command and of its ancestors are looked up without being merged, as the
+// usage line is only rendered.
+func (c *Command) requiredFlagsUsage() string {
+	flags := map[string]*flag.Flag{}
+	addRequired := func(f *flag.Flag) {
+		if f.Hidden || len(f.Deprecated) > 0 {
+			return
+		}
+		if annotation := f.Annotations[BashCompOneRequiredFlag]; len(annotation) == 0 || annotation[0] != "true" {
+			return
+		}
+		flags[f.Name] = f
+	}
+	c.Flags().VisitAll(addRequired)
+	for p := c; p != nil; p = p.Parent() {
+		p.PersistentFlags().VisitAll(addRequired)
+	}
+
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	required := make([]string, 0, len(names))
+	for _, name := range names {
+		f := flags[name]
+		usage := "--" + f.Name
+		if f.Value.Type() != "bool" {
+			value := "value"
+			if strings.Contains(f.Usage, "`") {
+				value, _ = flag.UnquoteUsage(f)
+			}
+			usage += " <" + value + ">"
+		}
+		required = append(required, usage)
+	}
+	return strings.Join(required, " ")
+}
+
+// ReconstructInvocation returns a shell-safe command line made of the command
+// path followed by the flags that were changed and their current values,
+// e.g. "app deploy --env prod --force". Values are quoted when needed, and the
+// values of the flags marked with MarkFlagSensitive are replaced by RedactedValue.
+// It is typically used to show the command that is about to run.
+func (c *Command) ReconstructInvocation() string {
+	words := strings.Split(c.CommandPath(), " ")
+	c.Flags().Visit(func(f *flag.Flag) {
+		name := "--" + f.Name
+		typ := f.Value.Type()
+		switch {
+		case isFlagSensitive(f) && len(f.NoOptDefVal) == 0:
+			words = append(words, name, RedactedValue)
+		case typ == "bool":
+			if f.Value.String() == "true" {
+				words = append(words, name)
+			} else {
+				words = append(words, name+"=false")
+			}
+		case strings.HasSuffix(typ, "Array"):
+			// Array values are not split on commas, so repeat the flag for each element.
+			for _, v := range sliceFlagValues(f) {
+				words = append(words, name, shellQuote(v))
+			}
+		case strings.HasSuffix(typ, "Slice"):
+			words = append(words, name, shellQuote(strings.TrimSuffix(strings.TrimPrefix(f.Value.String(), "["), "]")))
+		case strings.HasPrefix(typ, "stringTo"):
+			// Maps are listed in no particular order; sort their "key=value" entries.
+			values := sliceFlagValues(f)
+			sort.Strings(values)
+			words = append(words, name, shellQuote(csvJoin(values)))
+		case len(f.NoOptDefVal) > 0:
+			// The flags taking an optional value, such as count flags, only read it after "=".
+			words = append(words, name+"="+shellQuote(f.Value.String()))
+		default:
+			words = append(words, name, shellQuote(f.Value.String()))
+		}
+	})
+	return strings.Join(words, " ")
+}
+
+// sliceFlagValues returns the elements of a slice or array flag, whose
+// string representation is a bracketed list of comma separated values.
+func sliceFlagValues(f *flag.Flag) []string {
+	str := strings.TrimSuffix(strings.TrimPrefix(f.Value.String(), "["), "]")
+	if str == "" {
+		return nil
+	}
+	values, err := csv.NewReader(strings.NewReader(str)).Read()
+	if err != nil {
+		return []string{str}
+	}
+	return values
+}
+
+// csvJoin returns values as a line of comma separated values, quoted as needed.
+func csvJoin(values []string) string {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	_ = w.Write(values)
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
 // DebugFlags used to determine which flags have been assigned to which commands
 // and which persist.
 func (c *Command) DebugFlags() {
@@ -1315,6 +2481,26 @@ func (c *Command) hasNameOrAliasPrefix(prefix string) bool {
 	return false
 }
 
+// MarkAliasDeprecated deprecates one of the aliases of the command: the command keeps
+// working when invoked through it, but the given message is printed to stderr first.
+func (c *Command) MarkAliasDeprecated(alias, message string) error {
+	if !stringInSlice(alias, c.Aliases) {
+		return fmt.Errorf("MarkAliasDeprecated: alias %q of command %q does not exist", alias, c.CommandPath())
+	}
+	if c.deprecatedAliases == nil {
+		c.deprecatedAliases = map[string]string{}
+	}
+	c.deprecatedAliases[alias] = message
+	return nil
+}
+
+// AliasDeprecation returns the deprecation message of the alias of the command,
+// and whether the alias is deprecated.
+func (c *Command) AliasDeprecation(alias string) (string, bool) {
+	message, ok := c.deprecatedAliases[alias]
+	return message, ok
+}
+
 // NameAndAliases returns a list of the command name and all aliases
 func (c *Command) NameAndAliases() string {
 	return strings.Join(append([]string{c.Name()}, c.Aliases...), ", ")
@@ -1322,7 +2508,23 @@ func (c *Command) NameAndAliases() string {
 
 // HasExample determines if the command has example.
 func (c *Command) HasExample() bool {
-	return len(c.Example) > 0
+	return len(c.ExampleText()) > 0
+}
+
+// LongText returns the long message of the command, from LongFunc if set, or Long.
+func (c *Command) LongText() string {
+	if c.LongFunc != nil {
+		return c.LongFunc(c)
+	}
+	return c.Long
+}
+
+// ExampleText returns the examples of the command, from ExampleFunc if set, or Example.
+func (c *Command) ExampleText() string {
+	if c.ExampleFunc != nil {
+		return c.ExampleFunc(c)
+	}
+	return c.Example
 }
 
 // Runnable determines if the command is itself runnable.
@@ -1494,11 +2696,53 @@ func (c *Command) InheritedFlags() *flag.FlagSet {
 	return c.iflags
 }
 
+// InheritedFlagInfo describes a flag inherited by a command from its parents.
+type InheritedFlagInfo struct {
+	Flag *flag.Flag
+	// DefinedBy is the nearest ancestor defining the flag as a persistent flag.
+	DefinedBy *Command
+}
+
+// InheritedFlagInfos returns the flags inherited by c from its parents, as listed
+// by InheritedFlags, with the ancestors defining them.
+func (c *Command) InheritedFlagInfos() []InheritedFlagInfo {
+	var infos []InheritedFlagInfo
+	c.InheritedFlags().VisitAll(func(f *flag.Flag) {
+		info := InheritedFlagInfo{Flag: f}
+		for p := c.Parent(); p != nil; p = p.Parent() {
+			if p.PersistentFlags().Lookup(f.Name) != nil {
+				info.DefinedBy = p
+				break
+			}
+		}
+		infos = append(infos, info)
+	})
+	return infos
+}
+
 // NonInheritedFlags returns all flags which were not inherited from parent commands.
 func (c *Command) NonInheritedFlags() *flag.FlagSet {
 	return c.LocalFlags()
 }
 
+// EffectiveFlags returns all the flags the command parses when it is executed:
+// its local and persistent flags, the persistent flags inherited from its parents,
+// and the help and version flags. It is a new FlagSet on every call: adding flags
+// to it or removing flags from it has no effect on the command. The flags themselves
+// are shared with the command though, and must not be modified.
+func (c *Command) EffectiveFlags() *flag.FlagSet {
+	c.InitDefaultHelpFlag()
+	c.InitDefaultVersionFlag()
+
+	flags := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	flags.SortFlags = c.Flags().SortFlags
+	if c.globNormFunc != nil {
+		flags.SetNormalizeFunc(c.globNormFunc)
+	}
+	flags.AddFlagSet(c.Flags())
+	return flags
+}
+
 // PersistentFlags returns the persistent FlagSet specifically set in the current command.
 func (c *Command) PersistentFlags() *flag.FlagSet {
 	if c.pflags == nil {
@@ -1579,6 +2823,20 @@ func (c *Command) Flag(name string) (flag *flag.Flag) {
 	return
 }
 
+// LookupFlag returns the flag matching either a long name or a one-letter shorthand,
+// among the local and inherited flags of the command, or nil if there is none.
+func (c *Command) LookupFlag(nameOrShorthand string) *flag.Flag {
+	if len(nameOrShorthand) == 1 {
+		if f := c.Flags().ShorthandLookup(nameOrShorthand); f != nil {
+			return f
+		}
+		if f := c.InheritedFlags().ShorthandLookup(nameOrShorthand); f != nil {
+			return f
+		}
+	}
+	return c.Flag(nameOrShorthand)
+}
+
 // Recursively find matching persistent flag.
 func (c *Command) persistentFlag(name string) (flag *flag.Flag) {
 	if c.HasPersistentFlags() {
@@ -1606,8 +2864,14 @@ func (c *Command) ParseFlags(args []string) error {
 
 	// do it here after merging all flags and just before parse
 	c.Flags().ParseErrorsWhitelist = flag.ParseErrorsWhitelist(c.FParseErrWhitelist)
+	if c.StrictFlags {
+		c.Flags().ParseErrorsWhitelist = flag.ParseErrorsWhitelist{}
+	}
 
 	err := c.Flags().Parse(args)
+	if err == nil && c.StrictFlags {
+		err = c.checkStrictFlags(args)
+	}
 	// Print warnings if they occurred (e.g. deprecated flag messages).
 	if c.flagErrorBuf.Len()-beforeErrorBufLen > 0 && err == nil {
 		c.Print(c.flagErrorBuf.String())
@@ -1616,6 +2880,55 @@ func (c *Command) ParseFlags(args []string) error {
 	return err
 }
 
+// checkStrictFlags returns an error if a flag of args, successfully parsed, was given
+// a value starting with "-" as a separate argument, other than a negative number,
+// for StrictFlags.
+func (c *Command) checkStrictFlags(args []string) error {
+	flags := c.Flags()
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+
+		var f *flag.Flag
+		if strings.HasPrefix(arg, "--") {
+			if strings.Contains(arg, "=") {
+				continue
+			}
+			f = flags.Lookup(arg[2:])
+		} else {
+			// The last shorthand of a group takes the next argument as value,
+			// unless it is followed by its value.
+			for j := 1; j < len(arg); j++ {
+				f = flags.ShorthandLookup(arg[j : j+1])
+				if f == nil || (len(f.NoOptDefVal) == 0 && j+1 < len(arg)) {
+					f = nil
+					break
+				}
+			}
+		}
+		if f == nil || len(f.NoOptDefVal) > 0 || i+1 >= len(args) {
+			continue
+		}
+
+		i++
+		if value := args[i]; len(value) > 1 && value[0] == '-' && !isNumber(value) {
+			return fmt.Errorf("flag %s needs a value, got %q which looks like a flag; use --%s=%s if it is the value", arg, value, f.Name, value)
+		}
+	}
+	return nil
+}
+
+// isNumber returns true if s is a number, e.g. "-5" or "-1.5".
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
 // Parent returns a commands parent command.
 func (c *Command) Parent() *Command {
 	return c.parent
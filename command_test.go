@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -706,6 +708,32 @@ func TestOverwrittenFlag(t *testing.T) {
 	}
 }
 
+func TestInheritedFlagInfos(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	parentCmd := &Command{Use: "parent", Run: emptyRun}
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	rootCmd.AddCommand(parentCmd)
+	parentCmd.AddCommand(childCmd)
+
+	rootCmd.PersistentFlags().String("config", "", "")
+	parentCmd.PersistentFlags().String("namespace", "", "")
+	childCmd.Flags().String("output", "", "")
+
+	definedBy := map[string]*Command{}
+	for _, info := range childCmd.InheritedFlagInfos() {
+		definedBy[info.Flag.Name] = info.DefinedBy
+	}
+	if len(definedBy) != 2 {
+		t.Errorf("Expected 2 inherited flags, got %v", definedBy)
+	}
+	if definedBy["config"] != rootCmd {
+		t.Errorf(`Expected "config" to be defined by root, got %v`, definedBy["config"])
+	}
+	if definedBy["namespace"] != parentCmd {
+		t.Errorf(`Expected "namespace" to be defined by parent, got %v`, definedBy["namespace"])
+	}
+}
+
 func TestPersistentFlagsOnChild(t *testing.T) {
 	var childCmdArgs []string
 	rootCmd := &Command{Use: "root", Run: emptyRun}
@@ -1017,6 +1045,60 @@ func TestShorthandVersionFlagExecutedOnSubcommand(t *testing.T) {
 	checkStringContains(t, output, "root version 1.0.0")
 }
 
+func TestDefaultVersionCmd(t *testing.T) {
+	rootCmd := &Command{Use: "root", Version: "1.0.0", Run: emptyRun}
+	rootCmd.SetVersionTemplate(`customized version: {{.Version}}`)
+	rootCmd.AddCommand(&Command{Use: "sub", Run: emptyRun})
+	rootCmd.InitDefaultVersionCmd()
+
+	output, err := executeCommand(rootCmd, "version")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "customized version: 1.0.0")
+
+	output, err = executeCommand(rootCmd, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "version     Print the version of root")
+}
+
+func TestDefaultVersionCmdTemplateError(t *testing.T) {
+	rootCmd := &Command{Use: "root", Version: "1.0.0", Run: emptyRun}
+	rootCmd.SetVersionTemplate(`{{.Build}}`)
+	rootCmd.InitDefaultVersionCmd()
+
+	output, err := executeCommand(rootCmd, "version")
+	if err == nil {
+		t.Fatal("Expected an error for an invalid version template")
+	}
+	if count := strings.Count(output, "can't evaluate field Build"); count != 1 {
+		t.Errorf("Expected the error to be printed once, got %d times in:\n%s", count, output)
+	}
+}
+
+func TestDefaultVersionCmdNotAddedByDefault(t *testing.T) {
+	rootCmd := &Command{Use: "root", Version: "1.0.0", Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "sub", Run: emptyRun})
+
+	output, err := executeCommand(rootCmd, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringOmits(t, output, "Print the version")
+}
+
+func TestDefaultVersionCmdKeepsUserCommand(t *testing.T) {
+	rootCmd := &Command{Use: "root", Version: "1.0.0", Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "version", Short: "Show the build", Run: emptyRun})
+	rootCmd.InitDefaultVersionCmd()
+
+	if len(rootCmd.Commands()) != 1 || rootCmd.Commands()[0].Short != "Show the build" {
+		t.Errorf("Expected the version command of the user to be kept, got %v", rootCmd.Commands())
+	}
+}
+
 func TestVersionFlagOnlyAddedToRoot(t *testing.T) {
 	rootCmd := &Command{Use: "root", Version: "1.0.0", Run: emptyRun}
 	rootCmd.AddCommand(&Command{Use: "sub", Run: emptyRun})
@@ -1557,6 +1639,36 @@ func TestEnableCommandSortingIsDisabled(t *testing.T) {
 	EnableCommandSorting = true
 }
 
+func TestSetCommandSort(t *testing.T) {
+	lifecycle := map[string]int{"init": 1, "build": 2, "deploy": 3, "destroy": 4}
+
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	for _, name := range []string{"deploy", "build", "destroy", "init"} {
+		rootCmd.AddCommand(&Command{Use: name, Run: emptyRun})
+	}
+	rootCmd.SetCommandSort(func(cmds []*Command) []*Command {
+		sort.SliceStable(cmds, func(i, j int) bool {
+			return lifecycle[cmds[i].Name()] < lifecycle[cmds[j].Name()]
+		})
+		return cmds
+	})
+
+	var names []string
+	for _, c := range rootCmd.Commands() {
+		names = append(names, c.Name())
+	}
+	if got := strings.Join(names, " "); got != "init build deploy destroy" {
+		t.Errorf("Expected the commands in lifecycle order, got %q", got)
+	}
+
+	output, err := executeCommand(rootCmd, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	// The help command, which is not in the lifecycle, comes first.
+	checkStringContains(t, output, "  help        Help about any command\n  init        \n  build       \n  deploy      \n  destroy     \n")
+}
+
 func TestSetOutput(t *testing.T) {
 	c := &Command{}
 	c.SetOutput(nil)
@@ -1605,6 +1717,46 @@ func TestUsageStringRedirected(t *testing.T) {
 	}
 }
 
+func TestHelpStringRedirected(t *testing.T) {
+	c := &Command{}
+	var out bytes.Buffer
+	c.SetOut(&out)
+
+	c.SetHelpFunc(func(cmd *Command, args []string) {
+		cmd.Print("[stdout1]")
+		cmd.PrintErr("[stderr2]")
+	})
+
+	expected := "[stdout1][stderr2]"
+	if got := c.HelpString(); got != expected {
+		t.Errorf("Expected help string %q, got %q", expected, got)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected the output of the command to be restored, got %q", out.String())
+	}
+}
+
+func TestDefaultUsageFunc(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{Use: "child", Short: "child short", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+
+	rootCmd.SetUsageFunc(func(c *Command) error {
+		if err := c.DefaultUsageFunc()(c); err != nil {
+			return err
+		}
+		c.PrintErrln("See the docs.")
+		return nil
+	})
+
+	output, err := executeCommand(rootCmd, "child", "--unknown-flag")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	checkStringContains(t, output, "Usage:\n  root child [flags]")
+	checkStringContains(t, output, "See the docs.\n")
+}
+
 func TestFlagErrorFunc(t *testing.T) {
 	c := &Command{Use: "c", Run: emptyRun}
 
@@ -1958,3 +2110,1091 @@ func TestFParseErrWhitelistSiblingCommand(t *testing.T) {
 	}
 	checkStringContains(t, output, "unknown flag: --unknown")
 }
+
+func TestStrictFlagsIgnoresWhitelist(t *testing.T) {
+	c := &Command{
+		Use:         "c",
+		Run:         emptyRun,
+		StrictFlags: true,
+		FParseErrWhitelist: FParseErrWhitelist{
+			UnknownFlags: true,
+		},
+	}
+	c.Flags().BoolP("boola", "a", false, "a boolean flag")
+
+	output, err := executeCommand(c, "-a", "--unknown", "flag")
+	if err == nil {
+		t.Error("expected unknown flag error")
+	}
+	checkStringContains(t, output, "unknown flag: --unknown")
+}
+
+func TestStrictFlagsRejectsFlagLikeValues(t *testing.T) {
+	c := &Command{Use: "c", Args: ArbitraryArgs, Run: emptyRun, StrictFlags: true}
+	c.Flags().StringP("name", "n", "", "")
+	c.Flags().BoolP("verbose", "v", false, "")
+
+	for _, args := range [][]string{{"--name", "--forse"}, {"-vn", "-f"}} {
+		_, err := executeCommand(c, args...)
+		if err == nil {
+			t.Errorf("%q: expected an error for a value looking like a flag", args)
+			continue
+		}
+		checkStringContains(t, err.Error(), "which looks like a flag; use --name=")
+	}
+
+	for _, args := range [][]string{{"--name=--forse"}, {"-n-f"}, {"--name", "x", "--", "-f"}, {"-v", "-"}} {
+		if _, err := executeCommand(c, args...); err != nil {
+			t.Errorf("%q: unexpected error: %v", args, err)
+		}
+	}
+}
+
+func TestStrictFlagsAcceptsNegativeNumbers(t *testing.T) {
+	c := &Command{Use: "c", Args: ArbitraryArgs, Run: emptyRun, StrictFlags: true}
+	offset := c.Flags().IntP("offset", "o", 0, "")
+	delta := c.Flags().Float64("delta", 0, "")
+
+	if _, err := executeCommand(c, "--offset", "-5", "--delta", "-1.5"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *offset != -5 || *delta != -1.5 {
+		t.Errorf("Expected -5 and -1.5, got %d and %v", *offset, *delta)
+	}
+
+	if _, err := executeCommand(c, "-o", "-7"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := executeCommand(c, "--offset", "-x"); err == nil {
+		t.Error("Expected an error for a value looking like a flag")
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	runs := 0
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	initCmd := &Command{
+		Use:     "init",
+		Args:    NoArgs,
+		RunOnce: true,
+		Run:     func(*Command, []string) { runs++ },
+	}
+	rootCmd.AddCommand(initCmd)
+
+	// A failed validation of the arguments does not count as a run.
+	if _, err := executeCommand(rootCmd, "init", "extra"); err == nil {
+		t.Fatal("Expected an error for the extra argument")
+	}
+
+	if _, err := executeCommand(rootCmd, "init"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err := executeCommand(rootCmd, "init")
+	if err == nil {
+		t.Fatal("Expected an error for the second run")
+	}
+	expected := `command "root init" has already run and cannot run again`
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+	if runs != 1 {
+		t.Errorf("Expected the command to run once, ran %d times", runs)
+	}
+
+	// The guard applies to the command only.
+	if _, err := executeCommand(rootCmd); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRunOnceRetryAfterFailedValidation(t *testing.T) {
+	runs := 0
+	c := &Command{Use: "c", RunOnce: true, Run: func(*Command, []string) { runs++ }}
+	c.Flags().String("name", "", "")
+	_ = c.MarkFlagRequired("name")
+
+	_, err := executeCommand(c)
+	if err == nil {
+		t.Fatal("Expected an error for the missing required flag")
+	}
+	checkStringContains(t, err.Error(), `required flag(s) "name" not set`)
+
+	if _, err := executeCommand(c, "--name", "x"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("Expected the command to run once, ran %d times", runs)
+	}
+}
+
+func TestRunOnceDisabled(t *testing.T) {
+	runs := 0
+	c := &Command{Use: "c", Run: func(*Command, []string) { runs++ }}
+
+	for i := 0; i < 2; i++ {
+		if _, err := executeCommand(c); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if runs != 2 {
+		t.Errorf("Expected the command to run twice, ran %d times", runs)
+	}
+}
+
+func TestSetArgsString(t *testing.T) {
+	var gotArgs []string
+	c := &Command{
+		Use: "c",
+		Run: func(_ *Command, args []string) { gotArgs = args },
+	}
+	c.Flags().String("name", "", "")
+
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{`one two`, []string{"one", "two"}},
+		{`--name "John Smith" 'single quoted'`, []string{"single quoted"}},
+		{`escaped\ space "a \"quoted\" word"`, []string{"escaped space", `a "quoted" word`}},
+		{`''  "" end`, []string{"", "", "end"}},
+		{"one \\\n two", []string{"one", "two"}},
+		{"con\\\ntinued", []string{"continued"}},
+	}
+	for _, tc := range tests {
+		gotArgs = nil
+		if err := c.SetArgsString(tc.line); err != nil {
+			t.Fatalf("Unexpected error for %q: %v", tc.line, err)
+		}
+		c.SetOut(new(bytes.Buffer))
+		if err := c.Execute(); err != nil {
+			t.Fatalf("Unexpected error for %q: %v", tc.line, err)
+		}
+		if !reflect.DeepEqual(gotArgs, tc.expected) {
+			t.Errorf("For %q expected args %q, got %q", tc.line, tc.expected, gotArgs)
+		}
+	}
+
+	if name, _ := c.Flags().GetString("name"); name != "John Smith" {
+		t.Errorf("Expected quoted flag value %q, got %q", "John Smith", name)
+	}
+}
+
+func TestSetArgsStringUnbalancedQuote(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.SetArgs([]string{"unchanged"})
+
+	for _, line := range []string{`one "two`, `one 'two`, `one two\`} {
+		if err := c.SetArgsString(line); err == nil {
+			t.Errorf("Expected an error for %q", line)
+		}
+	}
+	if !reflect.DeepEqual(c.args, []string{"unchanged"}) {
+		t.Errorf("Expected args to be left untouched on error, got %q", c.args)
+	}
+}
+
+func TestReconstructInvocation(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	rootCmd.PersistentFlags().Bool("verbose", false, "")
+	deployCmd := &Command{Use: "deploy", Run: emptyRun}
+	deployCmd.Flags().String("env", "", "")
+	deployCmd.Flags().String("message", "", "")
+	deployCmd.Flags().Bool("force", false, "")
+	deployCmd.Flags().Bool("cache", true, "")
+	deployCmd.Flags().StringSlice("tags", nil, "")
+	deployCmd.Flags().StringArray("label", nil, "")
+	deployCmd.Flags().Int("replicas", 1, "")
+	rootCmd.AddCommand(deployCmd)
+
+	c, _, err := executeCommandC(rootCmd, "deploy", "--env", "prod", "--force", "--cache=false",
+		"--message", "it's ready", "--tags", "a,b", "--label", "x y", "--label", "z")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := c.ReconstructInvocation()
+	expected := `app deploy --cache=false --env prod --force --label 'x y' --label z --message 'it'\''s ready' --tags a,b`
+	if got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+
+	args, err := shellSplit(got)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if args[11] != "it's ready" {
+		t.Errorf("Expected the quoted value to be read back as %q, got %q", "it's ready", args[11])
+	}
+}
+
+func TestReconstructInvocationRoundTrip(t *testing.T) {
+	newDeployCmd := func() *Command {
+		c := &Command{Use: "deploy", Run: emptyRun}
+		c.Flags().CountP("verbose", "v", "")
+		c.Flags().StringToString("labels", nil, "")
+		c.Flags().StringSlice("tags", nil, "")
+		c.Flags().String("mode", "", "")
+		c.Flags().Lookup("mode").NoOptDefVal = "auto"
+		return c
+	}
+
+	deployCmd := newDeployCmd()
+	args := []string{"-vvv", "--labels", `team=a b,"note=x,y"`, "--tags", `"a,b",c`, "--mode=fast mode"}
+	if _, err := executeCommand(deployCmd, args...); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := deployCmd.ReconstructInvocation()
+	expected := `deploy --labels '"note=x,y",team=a b' --mode='fast mode' --tags '"a,b",c' --verbose=3`
+	if got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+
+	words, err := shellSplit(got)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	parsedCmd := newDeployCmd()
+	if _, err := executeCommand(parsedCmd, words[1:]...); err != nil {
+		t.Fatalf("Unexpected error parsing %q: %v", got, err)
+	}
+	for _, name := range []string{"verbose", "tags", "mode"} {
+		if want, got := deployCmd.Flags().Lookup(name).Value.String(), parsedCmd.Flags().Lookup(name).Value.String(); want != got {
+			t.Errorf("Expected --%s to be parsed back as %q, got %q", name, want, got)
+		}
+	}
+	labels, _ := parsedCmd.Flags().GetStringToString("labels")
+	if !reflect.DeepEqual(labels, map[string]string{"team": "a b", "note": "x,y"}) {
+		t.Errorf("Expected the labels to be parsed back, got %v", labels)
+	}
+}
+
+func TestValidateTree(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{Use: "child", Aliases: []string{"c"}, Run: emptyRun}
+	childCmd.Flags().String("format", "", "")
+	rootCmd.AddCommand(childCmd)
+
+	if err := rootCmd.ValidateTree(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err := childCmd.RegisterFlagCompletionFunc("format", func(*Command, []string, string) ([]string, ShellCompDirective) {
+		return nil, ShellCompDirectiveDefault
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	childCmd.ResetFlags()
+
+	rootCmd.AddCommand(&Command{Use: "copy", Aliases: []string{"c"}, Run: emptyRun})
+	rootCmd.AddCommand(&Command{Use: "child", Run: emptyRun})
+	rootCmd.AddCommand(&Command{Use: "list", ValidArgs: []string{"a"}, ValidArgsFunction: validArgsFunc, Run: emptyRun})
+
+	err := rootCmd.ValidateTree()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, err.Error(), `commands "root child" and "root copy" both use the name or alias "c"`)
+	checkStringContains(t, err.Error(), `commands "root child" and "root child" both use the name or alias "child"`)
+	checkStringContains(t, err.Error(), `command "root list" sets both ValidArgs and ValidArgsFunction`)
+	checkStringContains(t, err.Error(), `command "root child" has a completion function registered for missing flag "format"`)
+}
+
+func TestValidateTreeUnknownGroupID(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.AddGroup(&Group{ID: "core", Title: "Core Commands:"})
+	rootCmd.AddCommand(&Command{Use: "get", GroupID: "core", Run: emptyRun})
+	rootCmd.AddCommand(&Command{Use: "set", GroupID: "admin", Run: emptyRun})
+
+	err := rootCmd.ValidateTree()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, err.Error(), `command "root set" is in group "admin", which is not added to "root"`)
+	checkStringOmits(t, err.Error(), `"root get"`)
+}
+
+func TestValidateTreeMissingRequiredFlag(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().String("name", "", "")
+	if err := rootCmd.MarkFlagRequired("name"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := rootCmd.MarkFlagRequired("nmae"); err == nil {
+		t.Error("Expected an error for the missing flag")
+	}
+	if err := rootCmd.MarkPersistentFlagRequired("config"); err == nil {
+		t.Error("Expected an error for the missing persistent flag")
+	}
+
+	err := rootCmd.ValidateTree()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, err.Error(), `command "root" marks missing flag "nmae" as required`)
+	checkStringContains(t, err.Error(), `command "root" marks missing flag "config" as required`)
+	checkStringOmits(t, err.Error(), `"name"`)
+}
+
+func TestUsageErrors(t *testing.T) {
+	appErr := fmt.Errorf("application failure")
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{Use: "child", Args: ExactArgs(1), Run: emptyRun}
+	childCmd.Flags().String("name", "", "")
+	childCmd.MarkFlagRequired("name")
+	failCmd := &Command{Use: "fail", RunE: func(*Command, []string) error { return appErr }}
+	rootCmd.AddCommand(childCmd, failCmd)
+
+	usageCases := [][]string{
+		{"unknown"},
+		{"child", "arg"},
+		{"child", "--unknown", "--name", "n", "arg"},
+		{"child", "--name", "n"},
+	}
+	for _, args := range usageCases {
+		_, err := executeCommand(rootCmd, args...)
+		if err == nil {
+			t.Fatalf("Expected an error for %v", args)
+		}
+		if !IsUsageError(err) {
+			t.Errorf("Expected a usage error for %v, got %v", args, err)
+		}
+	}
+
+	_, err := executeCommand(rootCmd, "fail")
+	if err != appErr {
+		t.Errorf("Expected the application error to be returned unchanged, got %v", err)
+	}
+	if IsUsageError(err) {
+		t.Errorf("Expected an application error, got a usage error")
+	}
+}
+
+type causeError struct{ cause error }
+
+func (e causeError) Error() string { return "wrapped: " + e.cause.Error() }
+func (e causeError) Cause() error  { return e.cause }
+
+func TestIsUsageErrorWrapped(t *testing.T) {
+	usageErr := newUsageError(fmt.Errorf("unknown flag"))
+	if !IsUsageError(causeError{usageErr}) {
+		t.Error("Expected a usage error wrapped through Cause to be recognized")
+	}
+	if !IsUsageError(&ExitError{Code: 2, Err: causeError{usageErr}}) {
+		t.Error("Expected a usage error wrapped through Unwrap and Cause to be recognized")
+	}
+	if IsUsageError(causeError{fmt.Errorf("failure")}) || IsUsageError(nil) {
+		t.Error("Expected no usage error")
+	}
+}
+
+type validationErrors []error
+
+func (v validationErrors) Error() string {
+	var msgs []string
+	for _, err := range v {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func TestSetValidate(t *testing.T) {
+	ran := false
+	c := &Command{Use: "c", Run: func(*Command, []string) { ran = true }}
+	c.Flags().Int("min", 0, "")
+	c.Flags().Int("max", 10, "")
+	c.Flags().String("format", "text", "")
+	c.SetValidate(func(cmd *Command) error {
+		var errs validationErrors
+		min, _ := cmd.Flags().GetInt("min")
+		max, _ := cmd.Flags().GetInt("max")
+		if min > max {
+			errs = append(errs, fmt.Errorf("--min (%d) must not be greater than --max (%d)", min, max))
+		}
+		if format, _ := cmd.Flags().GetString("format"); format != "text" && format != "json" {
+			errs = append(errs, fmt.Errorf("--format must be text or json, got %q", format))
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+		return nil
+	})
+
+	output, err := executeCommand(c, "--min", "20", "--format", "xml")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if ran {
+		t.Error("Run should not be called when validation fails")
+	}
+	if !IsUsageError(err) {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+	checkStringContains(t, output, "--min (20) must not be greater than --max (10)")
+	checkStringContains(t, output, `--format must be text or json, got "xml"`)
+
+	if _, err := executeCommand(c, "--min", "5", "--format", "json"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !ran {
+		t.Error("Run should be called when validation succeeds")
+	}
+}
+
+func TestLookupFlag(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.PersistentFlags().StringP("config", "c", "", "")
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	childCmd.Flags().BoolP("verbose", "v", false, "")
+	rootCmd.AddCommand(childCmd)
+
+	tests := []struct {
+		nameOrShorthand string
+		expected        string
+	}{
+		{"verbose", "verbose"},
+		{"v", "verbose"},
+		{"config", "config"},
+		{"c", "config"},
+	}
+	for _, tc := range tests {
+		f := childCmd.LookupFlag(tc.nameOrShorthand)
+		if f == nil {
+			t.Errorf("Expected to find flag %q for %q", tc.expected, tc.nameOrShorthand)
+			continue
+		}
+		if f.Name != tc.expected {
+			t.Errorf("Expected flag %q for %q, got %q", tc.expected, tc.nameOrShorthand, f.Name)
+		}
+	}
+
+	for _, name := range []string{"unknown", "x"} {
+		if f := childCmd.LookupFlag(name); f != nil {
+			t.Errorf("Expected no flag for %q, got %q", name, f.Name)
+		}
+	}
+}
+
+func TestEmptyInvocationRunE(t *testing.T) {
+	var called bool
+	rootCmd := &Command{Use: "root", Long: "Root long description"}
+	rootCmd.Flags().Bool("verbose", false, "")
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+
+	// Without handler, the help is printed.
+	output, err := executeCommand(rootCmd)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, rootCmd.Long)
+
+	rootCmd.SetEmptyInvocationRunE(func(cmd *Command, args []string) error {
+		called = true
+		return nil
+	})
+
+	output, err = executeCommand(rootCmd)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !called {
+		t.Error("Expected the empty invocation handler to be called")
+	}
+	checkStringOmits(t, output, rootCmd.Long)
+
+	called = false
+	if _, err = executeCommand(rootCmd, "child"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if called {
+		t.Error("Expected the empty invocation handler not to be called for a subcommand")
+	}
+
+	output, err = executeCommand(rootCmd, "--verbose")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if called {
+		t.Error("Expected the empty invocation handler not to be called when flags are given")
+	}
+	checkStringContains(t, output, rootCmd.Long)
+
+	rootCmd.SetEmptyInvocationRunE(func(cmd *Command, args []string) error {
+		return fmt.Errorf("default action failed")
+	})
+	if _, err = executeCommand(rootCmd); err == nil || err.Error() != "default action failed" {
+		t.Errorf("Expected the error of the handler, got: %v", err)
+	}
+}
+
+func TestUnknownCommandHandler(t *testing.T) {
+	var handledCmd *Command
+	var handledArgs []string
+	var handlerErr error
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.PersistentFlags().Bool("verbose", false, "")
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+	rootCmd.SetUnknownCommandHandler(func(cmd *Command, args []string) error {
+		handledCmd = cmd
+		handledArgs = args
+		return handlerErr
+	})
+
+	output, err := executeCommand(rootCmd, "--verbose", "plugin", "arg1", "--flag")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if output != "" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+	if handledCmd != rootCmd {
+		t.Errorf("Expected the handler to receive the root command")
+	}
+	expected := []string{"plugin", "--verbose", "arg1", "--flag"}
+	if !reflect.DeepEqual(handledArgs, expected) {
+		t.Errorf("Expected args %v, got %v", expected, handledArgs)
+	}
+
+	// Known subcommands are not intercepted.
+	handledArgs = nil
+	if _, err = executeCommand(rootCmd, "child"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if handledArgs != nil {
+		t.Errorf("Expected the handler not to be called for a known subcommand")
+	}
+
+	// Unhandled invocations fall through to the unknown command error.
+	handlerErr = ErrUnknownCommand
+	output, err = executeCommand(rootCmd, "chidl")
+	if err == nil {
+		t.Fatal("Expected an unknown command error")
+	}
+	checkStringContains(t, output, `unknown command "chidl" for "root"`)
+	checkStringContains(t, output, "Did you mean this?")
+
+	// The errors of the handled invocations are returned.
+	handlerErr = fmt.Errorf("plugin failed")
+	output, err = executeCommand(rootCmd, "plugin")
+	if err != handlerErr {
+		t.Errorf("Expected the error of the handler, got %v", err)
+	}
+	checkStringContains(t, output, "Error: plugin failed\n")
+	checkStringOmits(t, output, "unknown command")
+}
+
+func TestRequiredFlagsInUseLine(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	rootCmd.PersistentFlags().String("token", "", "the `secret` token")
+	createCmd := &Command{Use: "create <name>", Run: emptyRun}
+	createCmd.Flags().String("type", "", "type of the resource")
+	createCmd.Flags().Bool("force", false, "force creation")
+	createCmd.Flags().String("description", "", "description of the resource")
+	rootCmd.AddCommand(createCmd)
+	_ = createCmd.MarkFlagRequired("type")
+	_ = createCmd.MarkFlagRequired("force")
+	_ = rootCmd.MarkPersistentFlagRequired("token")
+
+	// The required flags are only added on demand.
+	expected := "app create <name> [flags]"
+	if got := createCmd.UseLine(); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+
+	rootCmd.RequiredFlagsInUseLine = true
+	expected = "app create <name> --force --token <secret> --type <value> [flags]"
+	if got := createCmd.UseLine(); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+
+	output, err := executeCommand(rootCmd, "create", "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "Usage:\n  "+expected+"\n")
+	checkStringOmits(t, output, "--description <")
+
+}
+
+func TestRequiredFlagsInUseLineEmptyAnnotation(t *testing.T) {
+	c := &Command{Use: "c", RequiredFlagsInUseLine: true, Run: emptyRun}
+	c.Flags().String("name", "", "")
+	_ = c.Flags().SetAnnotation("name", BashCompOneRequiredFlag, []string{})
+
+	expected := "c [flags]"
+	if got := c.UseLine(); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+}
+
+func TestEffectiveFlags(t *testing.T) {
+	rootCmd := &Command{Use: "root", Version: "1.0.0", Run: emptyRun}
+	rootCmd.PersistentFlags().String("config", "", "")
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	childCmd.Flags().Bool("local", false, "")
+	childCmd.PersistentFlags().Bool("persistent", false, "")
+	rootCmd.AddCommand(childCmd)
+
+	flags := childCmd.EffectiveFlags()
+	var names []string
+	flags.VisitAll(func(f *pflag.Flag) {
+		names = append(names, f.Name)
+	})
+	expected := []string{"config", "help", "local", "persistent"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected flags %v, got %v", expected, names)
+	}
+
+	// The returned set is a snapshot.
+	flags.Bool("extra", false, "")
+	if childCmd.Flags().Lookup("extra") != nil {
+		t.Error("Expected flags added to the effective flags not to be added to the command")
+	}
+
+	if rootCmd.EffectiveFlags().Lookup("version") == nil {
+		t.Error("Expected the version flag in the effective flags of the root command")
+	}
+}
+
+func TestStructuredExamples(t *testing.T) {
+	c := &Command{
+		Use: "get",
+		Example: `  # List the pods
+  # of all the namespaces
+  $ app get pods --all-namespaces
+
+  app get services
+
+  # Watch a deployment
+  app get deployment web \
+    --watch`,
+		Run: emptyRun,
+	}
+
+	expected := []Example{
+		{Description: "List the pods of all the namespaces", Command: "app get pods --all-namespaces"},
+		{Command: "app get services"},
+		{Description: "Watch a deployment", Command: "app get deployment web --watch"},
+	}
+	if got := c.StructuredExamples(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, got)
+	}
+
+	if got := (&Command{Use: "c"}).StructuredExamples(); len(got) != 0 {
+		t.Errorf("Expected no examples, got %#v", got)
+	}
+}
+
+func TestExamplesMatchCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	rootCmd.PersistentFlags().StringP("namespace", "n", "", "")
+	getCmd := &Command{
+		Use:       "get",
+		ValidArgs: []string{"pods\tThe pods", "services"},
+		Example: `  # List the pods
+  $ app get pods
+  app get -n default services # the services
+  app get --namespace=default --all nodes`,
+		Run: emptyRun,
+	}
+	getCmd.Flags().Bool("all", false, "")
+	describeCmd := &Command{
+		Use: "describe",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+			if len(args) == 0 {
+				return []string{"pods", "services"}, ShellCompDirectiveNoFileComp
+			}
+			return nil, ShellCompDirectiveNoFileComp
+		},
+		Example: "app describe pods my-pod\napp describe volumes my-volume",
+		Run:     emptyRun,
+	}
+	rootCmd.AddCommand(getCmd, describeCmd)
+
+	errs := getCmd.ExamplesMatchCompletion()
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v", errs)
+	}
+	checkStringContains(t, errs[0].Error(), `argument "nodes" is not offered by completion`)
+
+	// Arguments for which the function returns no choice are not checked.
+	errs = describeCmd.ExamplesMatchCompletion()
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v", errs)
+	}
+	checkStringContains(t, errs[0].Error(), `argument "volumes" is not offered by completion`)
+
+	if errs := rootCmd.ExamplesMatchCompletion(); errs != nil {
+		t.Errorf("Expected no error for a command without completion, got %v", errs)
+	}
+}
+
+func TestRawUseLine(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	syncCmd := &Command{Use: "sync (--all | <source>...) [--dry-run] <destination>", Run: emptyRun}
+	syncCmd.Flags().Bool("all", false, "")
+	syncCmd.Flags().Bool("dry-run", false, "")
+	syncCmd.Flags().String("token", "", "")
+	_ = syncCmd.MarkFlagRequired("token")
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.SetRawUseLine(true)
+	expected := "app sync (--all | <source>...) [--dry-run] <destination>"
+	if got := syncCmd.UseLine(); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+
+	output, err := executeCommand(rootCmd, "sync", "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "Usage:\n  "+expected+"\n")
+}
+
+func TestMarkAliasDeprecated(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	var runs int
+	removeCmd := &Command{
+		Use:     "remove",
+		Aliases: []string{"rm", "del"},
+		Run:     func(*Command, []string) { runs++ },
+	}
+	rootCmd.AddCommand(removeCmd)
+
+	if err := removeCmd.MarkAliasDeprecated("delete", "use rm instead"); err == nil {
+		t.Error("Expected an error for an unknown alias")
+	}
+	if err := removeCmd.MarkAliasDeprecated("del", "use rm instead"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output, err := executeCommand(rootCmd, "del")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, `Alias "del" is deprecated, use rm instead`)
+
+	for _, name := range []string{"remove", "rm"} {
+		output, err = executeCommand(rootCmd, name)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		checkStringOmits(t, output, "deprecated")
+	}
+
+	if runs != 3 {
+		t.Errorf("Expected the command to run 3 times, ran %d times", runs)
+	}
+}
+
+func TestExclusiveCommandGroup(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	encryptCmd := &Command{Use: "encrypt", Aliases: []string{"enc"}, Run: emptyRun}
+	decryptCmd := &Command{Use: "decrypt", Aliases: []string{"dec"}, Run: emptyRun}
+	rootCmd.AddCommand(encryptCmd, decryptCmd)
+
+	if err := rootCmd.AddExclusiveCommandGroup("encrypt", "sign"); err == nil {
+		t.Error("Expected an error for an unknown subcommand")
+	}
+	if err := rootCmd.AddExclusiveCommandGroup("encrypt", "decrypt"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if groups := rootCmd.ExclusiveCommandGroups(); len(groups) != 1 || len(groups[0]) != 2 {
+		t.Errorf("Expected 1 group of 2 commands, got %v", groups)
+	}
+
+	// An argument named like another command of the group, e.g. a file, is valid.
+	if _, err := executeCommand(rootCmd, "encrypt", "dec"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	output, _ := executeCommand(rootCmd, "encrpyt")
+	checkStringContains(t, output, "Did you mean this?\n\tencrypt\n\nOnly one of these commands can be used: encrypt, decrypt\n")
+}
+
+func TestLongFuncAndExampleFunc(t *testing.T) {
+	var calls int
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	pluginCmd := &Command{
+		Use:  "plugin",
+		Long: "static long",
+		LongFunc: func(*Command) string {
+			calls++
+			return "Installed plugins: foo, bar"
+		},
+		ExampleFunc: func(*Command) string {
+			calls++
+			return "  root plugin foo"
+		},
+		Run: emptyRun,
+	}
+	rootCmd.AddCommand(pluginCmd)
+
+	if _, err := executeCommand(rootCmd, "plugin"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected LongFunc and ExampleFunc not to be called, got %d calls", calls)
+	}
+
+	output, err := executeCommand(rootCmd, "plugin", "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "Installed plugins: foo, bar\n")
+	checkStringContains(t, output, "Examples:\n  root plugin foo\n")
+	checkStringOmits(t, output, "static long")
+	if calls != 2 {
+		t.Errorf("Expected LongFunc and ExampleFunc to be called once each, got %d calls", calls)
+	}
+
+	pluginCmd.LongFunc = nil
+	if got := pluginCmd.LongText(); got != "static long" {
+		t.Errorf("Expected the static Long as fallback, got %q", got)
+	}
+}
+
+func TestExecuteArgsConcurrent(t *testing.T) {
+	type key struct{}
+	type result struct {
+		ctxValue interface{}
+		name     string
+		args     []string
+	}
+	results := make(chan result, 2)
+
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	getCmd := &Command{
+		Use: "get",
+		RunE: func(cmd *Command, args []string) error {
+			name, err := cmd.Flags().GetString("name")
+			results <- result{cmd.Context().Value(key{}), name, args}
+			return err
+		},
+	}
+	getCmd.Flags().String("name", "default", "")
+	rootCmd.AddCommand(getCmd)
+	rootCmd.SetOut(new(bytes.Buffer))
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- rootCmd.ExecuteArgs(context.WithValue(context.Background(), key{}, "first"), []string{"get", "--name", "one", "a"})
+	}()
+	go func() {
+		errs <- rootCmd.ExecuteArgs(context.WithValue(context.Background(), key{}, "second"), []string{"get", "b"})
+	}()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	close(results)
+
+	for r := range results {
+		switch r.ctxValue {
+		case "first":
+			if r.name != "one" || !reflect.DeepEqual(r.args, []string{"a"}) {
+				t.Errorf("First execution got name %q and args %v", r.name, r.args)
+			}
+		case "second":
+			if r.name != "default" || !reflect.DeepEqual(r.args, []string{"b"}) {
+				t.Errorf("Second execution got name %q and args %v", r.name, r.args)
+			}
+		default:
+			t.Errorf("Unexpected context value %v", r.ctxValue)
+		}
+	}
+
+	if rootCmd.args != nil {
+		t.Errorf("Expected the arguments of the command to be left unset, got %v", rootCmd.args)
+	}
+}
+
+func TestExecuteArgsRestoresFlags(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	getCmd := &Command{Use: "get", Run: emptyRun}
+	getCmd.Flags().String("name", "default", "")
+	getCmd.Flags().Int("count", 1, "")
+	rootCmd.AddCommand(getCmd)
+
+	// A value set in code before the execution is kept.
+	if err := getCmd.Flags().Set("name", "configured"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := rootCmd.ExecuteArgs(context.Background(), []string{"get", "--name", "one", "--count", "3"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if name, _ := getCmd.Flags().GetString("name"); name != "configured" {
+		t.Errorf("Expected the name set in code to be restored, got %q", name)
+	}
+	if !getCmd.Flags().Lookup("name").Changed {
+		t.Error("Expected the name to still be changed")
+	}
+	if count, _ := getCmd.Flags().GetInt("count"); count != 1 || getCmd.Flags().Lookup("count").Changed {
+		t.Errorf("Expected the count to be reset, got %d", count)
+	}
+}
+
+func TestExecuteArgsNested(t *testing.T) {
+	var names []string
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	getCmd := &Command{
+		Use: "get",
+		Run: func(cmd *Command, args []string) {
+			name, _ := cmd.Flags().GetString("name")
+			names = append(names, name)
+		},
+	}
+	getCmd.Flags().String("name", "default", "")
+	replCmd := &Command{
+		Use: "repl",
+		RunE: func(cmd *Command, args []string) error {
+			for _, line := range [][]string{{"get", "--name", "one"}, {"get"}} {
+				if err := cmd.Root().ExecuteArgs(cmd.Context(), line); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	rootCmd.AddCommand(getCmd, replCmd)
+
+	done := make(chan error)
+	go func() {
+		done <- rootCmd.ExecuteArgs(context.Background(), []string{"repl"})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Nested call to ExecuteArgs did not return")
+	}
+
+	if !reflect.DeepEqual(names, []string{"one", "default"}) {
+		t.Errorf("Expected the nested executions to get the names [one default], got %v", names)
+	}
+}
+
+func TestSubcommandDescriptionFunc(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	getCmd := &Command{Use: "get", Short: "Get a resource", Run: emptyRun}
+	auditCmd := &Command{
+		Use:         "audit",
+		Short:       "Audit the accounts",
+		Annotations: map[string]string{"tier": "enterprise"},
+		Run:         emptyRun,
+	}
+	rootCmd.AddCommand(getCmd, auditCmd)
+
+	output, err := executeCommand(rootCmd, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "  audit       Audit the accounts\n")
+
+	rootCmd.SetSubcommandDescriptionFunc(func(c *Command) string {
+		if tier := c.Annotations["tier"]; tier != "" {
+			return c.Short + " [" + tier + "]"
+		}
+		return c.Short
+	})
+	output, err = executeCommand(rootCmd, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "  audit       Audit the accounts [enterprise]\n")
+	checkStringContains(t, output, "  get         Get a resource\n")
+}
+
+func TestAddCommandInGroup(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.AddGroup(
+		&Group{ID: "basic", Title: "Basic Commands:"},
+		&Group{ID: "deploy", Title: "Deploy Commands:"},
+	)
+	createCmd := &Command{Use: "create", Short: "Create a resource", Run: emptyRun}
+	rolloutCmd := &Command{Use: "rollout", Short: "Manage a rollout", Run: emptyRun}
+	rootCmd.AddCommandInGroup("basic", createCmd)
+	rootCmd.AddCommandInGroup("deploy", rolloutCmd)
+	rootCmd.AddCommand(&Command{Use: "version", Short: "Print the version", Run: emptyRun})
+
+	if createCmd.GroupID != "basic" || rolloutCmd.GroupID != "deploy" {
+		t.Errorf("Expected the group IDs to be set, got %q and %q", createCmd.GroupID, rolloutCmd.GroupID)
+	}
+
+	output, err := executeCommand(rootCmd, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "\n\nBasic Commands:\n  create      Create a resource\n\n")
+	checkStringContains(t, output, "\n\nDeploy Commands:\n  rollout     Manage a rollout\n\n")
+	checkStringContains(t, output, "\n\nAdditional Commands:\n  help        Help about any command\n  version     Print the version\n")
+	checkStringOmits(t, output, "Available Commands:")
+}
+
+func TestAddCommandInUndefinedGroup(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for the undefined group")
+		}
+	}()
+	rootCmd.AddCommandInGroup("basic", &Command{Use: "create", Run: emptyRun})
+}
+
+func TestUndefinedGroupIDPanics(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "create", GroupID: "basic", Run: emptyRun})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for the undefined group")
+		}
+	}()
+	_, _ = executeCommand(rootCmd, "create")
+}
+
+func TestCheckInvocation(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	getCmd := &Command{Use: "get", Args: ExactArgs(1), Run: func(*Command, []string) { t.Error("Unexpected run") }}
+	getCmd.Flags().StringSlice("tag", nil, "")
+	getCmd.Flags().String("name", "", "")
+	getCmd.Flags().Bool("json", false, "")
+	getCmd.Flags().Bool("yaml", false, "")
+	getCmd.MarkFlagsMutuallyExclusive("json", "yaml")
+	_ = getCmd.MarkFlagRequired("name")
+	rootCmd.AddCommand(getCmd)
+
+	cmd, err := rootCmd.CheckInvocation([]string{"get", "--name", "n", "--tag", "a", "x"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd != getCmd {
+		t.Errorf("Expected the get command, got %q", cmd.CommandPath())
+	}
+	if _, err := rootCmd.CheckInvocation([]string{"get", "--name", "n", "--tag", "b", "y"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tag := getCmd.Flags().Lookup("tag")
+	if tag.Changed || tag.Value.String() != "[]" {
+		t.Errorf("Expected the flags to be restored, got --tag=%s (changed: %v)", tag.Value, tag.Changed)
+	}
+
+	for _, args := range [][]string{
+		{"get", "x"},
+		{"get", "--name", "n"},
+		{"get", "--name", "n", "--json", "--yaml", "x"},
+		{"get", "--unknown", "x"},
+	} {
+		if _, err := rootCmd.CheckInvocation(args); err == nil {
+			t.Errorf("Expected an error for %q", args)
+		}
+	}
+}
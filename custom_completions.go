@@ -1,9 +1,12 @@
 package cobra
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/spf13/pflag"
@@ -16,11 +19,21 @@ const (
 	// ShellCompNoDescRequestCmd is the name of the hidden command that is used to request
 	// completion results without their description.  It is used by the shell completion scripts.
 	ShellCompNoDescRequestCmd = "__completeNoDesc"
+	// ShellCompJSONRequestCmd is the name of the hidden command that is used to request
+	// completion results as JSON.  It is meant for editor integrations.
+	ShellCompJSONRequestCmd = "__complete-json"
+
+	// FlagCompletionDescAnnotation is the flag annotation holding the description
+	// shown for the flag in shell completion, in place of its usage.
+	FlagCompletionDescAnnotation = "cobra_annotation_completion_desc"
 )
 
 // Global map of flag completion functions.
 var flagCompletionFunctions = map[*pflag.Flag]func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective){}
 
+// Global map of the commands on which flag completion functions were registered.
+var flagCompletionCommands = map[*pflag.Flag]*Command{}
+
 // ShellCompDirective is a bit map representing the different behaviors the shell
 // can be instructed to have once completions have been provided.
 type ShellCompDirective int
@@ -38,12 +51,48 @@ const (
 	// This currently does not work for zsh or bash < 4
 	ShellCompDirectiveNoFileComp
 
+	// The next two bits are ShellCompDirectiveFilterFileExt and ShellCompDirectiveFilterDirs
+	// in upstream cobra; they are skipped to keep the values of the directives the same.
+	_
+	_
+
+	// ShellCompDirectiveKeepOrder indicates that the shell should preserve the order
+	// in which the completions are provided, and prevents them from being sorted when
+	// CompletionOptions.SortResults is set.
+	// This currently only works for bash >= 4.4.
+	ShellCompDirectiveKeepOrder
+
 	// ShellCompDirectiveDefault indicates to let the shell perform its default
 	// behavior after completions have been provided.
 	ShellCompDirectiveDefault ShellCompDirective = 0
 )
 
+// CompletionOptions are the options to control shell completion, set on the root command.
+type CompletionOptions struct {
+	// SortResults sorts the completion choices before they are returned to the shell,
+	// unless the ShellCompDirectiveKeepOrder directive is returned. It gives a stable
+	// output when the completion functions build their choices from maps, e.g. in tests.
+	SortResults bool
+}
+
+// CompletionPlaceholder returns a completion entry that describes the argument
+// expected at the current position instead of proposing an actual value,
+// e.g. CompletionPlaceholder("<name>", "The resource name").
+// It is useful when the set of valid values cannot be enumerated.
+// The shell scripts render placeholders as guidance only: they are shown to
+// the user when nothing else matches but are never inserted on the command-line.
+func CompletionPlaceholder(name, description string) string {
+	return fmt.Sprintf("\t%s  %s", name, description)
+}
+
+// isCompletionPlaceholder returns true if comp has an empty value followed by a description.
+func isCompletionPlaceholder(comp string) bool {
+	return len(comp) > 1 && comp[0] == '\t'
+}
+
 // RegisterFlagCompletionFunc should be called to register a function to provide completion for a flag.
+// It returns an error if a function is already registered for the flag; use
+// ReplaceFlagCompletionFunc to override it intentionally.
 func (c *Command) RegisterFlagCompletionFunc(flagName string, f func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective)) error {
 	flag := c.Flag(flagName)
 	if flag == nil {
@@ -53,9 +102,130 @@ func (c *Command) RegisterFlagCompletionFunc(flagName string, f func(cmd *Comman
 		return fmt.Errorf("RegisterFlagCompletionFunc: flag '%s' already registered", flagName)
 	}
 	flagCompletionFunctions[flag] = f
+	flagCompletionCommands[flag] = c
+	return nil
+}
+
+// ReplaceFlagCompletionFunc registers a function to provide completion for a flag,
+// replacing the function previously registered for it, if any.
+func (c *Command) ReplaceFlagCompletionFunc(flagName string, f func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective)) error {
+	flag := c.Flag(flagName)
+	if flag == nil {
+		return fmt.Errorf("ReplaceFlagCompletionFunc: flag '%s' does not exist", flagName)
+	}
+	flagCompletionFunctions[flag] = f
+	flagCompletionCommands[flag] = c
+	return nil
+}
+
+// FlagCompletionFuncs returns the set of the names of the flags of the command,
+// including the inherited ones, for which a completion function is registered.
+func (c *Command) FlagCompletionFuncs() map[string]bool {
+	names := map[string]bool{}
+	c.mergePersistentFlags()
+	c.Flags().VisitAll(func(flag *pflag.Flag) {
+		if _, ok := flagCompletionFunctions[flag]; ok {
+			names[flag.Name] = true
+		}
+	})
+	return names
+}
+
+// SetFlagCompletionDescription sets a short description to be shown for the named flag
+// in shell completion, in place of its usage which is often too long for completion menus.
+// The usage is still used for the help output.
+func (c *Command) SetFlagCompletionDescription(flagName, description string) error {
+	flag := c.Flag(flagName)
+	if flag == nil {
+		return fmt.Errorf("SetFlagCompletionDescription: flag '%s' does not exist", flagName)
+	}
+	if flag.Annotations == nil {
+		flag.Annotations = map[string][]string{}
+	}
+	flag.Annotations[FlagCompletionDescAnnotation] = []string{description}
 	return nil
 }
 
+// flagCompletionDescription returns the description of the flag to be used in shell completion.
+func flagCompletionDescription(flag *pflag.Flag) string {
+	if desc, ok := flag.Annotations[FlagCompletionDescAnnotation]; ok && len(desc) > 0 {
+		return desc[0]
+	}
+	return flag.Usage
+}
+
+// CompoundValueCompletion returns a flag completion function for flags whose value is
+// made of segments separated by separator, e.g. "a,b,c" with separator ",".
+// The function f receives the already typed segments, including the last separator
+// (e.g. "a,b,"), and the segment being completed (e.g. "c"); it returns the
+// completions for that segment only, which are then prefixed with the typed portion
+// so that the shell can match them against the whole value.
+func CompoundValueCompletion(separator string, f func(cmd *Command, args []string, typed, toComplete string) ([]string, ShellCompDirective)) func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+	return func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+		typed := ""
+		if idx := strings.LastIndex(toComplete, separator); idx >= 0 {
+			typed = toComplete[:idx+len(separator)]
+			toComplete = toComplete[idx+len(separator):]
+		}
+		comps, directive := f(cmd, args, typed, toComplete)
+		for i, comp := range comps {
+			if !isCompletionPlaceholder(comp) {
+				comps[i] = typed + comp
+			}
+		}
+		return comps, directive
+	}
+}
+
+// CommaListCompletion returns a flag completion function for flags taking a comma-separated
+// list of values, e.g. "--tags a,b,c". It completes the next element of the list among values,
+// leaving out the elements already in the list, and does not add a space after it so that
+// another element can be appended.
+func CommaListCompletion(values []string) func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+	return CompoundValueCompletion(",", func(cmd *Command, args []string, typed, toComplete string) ([]string, ShellCompDirective) {
+		listed := map[string]bool{}
+		for _, value := range strings.Split(typed, ",") {
+			listed[value] = true
+		}
+		var comps []string
+		for _, value := range values {
+			if !listed[value] && strings.HasPrefix(value, toComplete) {
+				comps = append(comps, value)
+			}
+		}
+		return comps, ShellCompDirectiveNoSpace | ShellCompDirectiveNoFileComp
+	})
+}
+
+// KeyValueCompletion returns a flag completion function for flags taking a key=value pair,
+// e.g. "--set key=value". Until "=" is typed, it completes the key among keys, followed by "="
+// and without adding a space. Then it completes the value among those returned by values
+// for the typed key.
+func KeyValueCompletion(keys []string, values func(key string) []string) func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+	return func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+		var comps []string
+		idx := strings.Index(toComplete, "=")
+		if idx < 0 {
+			for _, key := range keys {
+				if strings.HasPrefix(key, toComplete) {
+					comps = append(comps, key+"=")
+				}
+			}
+			return comps, ShellCompDirectiveNoSpace | ShellCompDirectiveNoFileComp
+		}
+
+		key, value := toComplete[:idx], toComplete[idx+1:]
+		if values != nil {
+			for _, v := range values(key) {
+				if strings.HasPrefix(v, value) {
+					comps = append(comps, key+"="+v)
+				}
+			}
+		}
+		return comps, ShellCompDirectiveNoFileComp
+	}
+}
+
 // Returns a string listing the different directive enabled in the specified parameter
 func (d ShellCompDirective) string() string {
 	var directives []string
@@ -68,11 +238,14 @@ func (d ShellCompDirective) string() string {
 	if d&ShellCompDirectiveNoFileComp != 0 {
 		directives = append(directives, "ShellCompDirectiveNoFileComp")
 	}
+	if d&ShellCompDirectiveKeepOrder != 0 {
+		directives = append(directives, "ShellCompDirectiveKeepOrder")
+	}
 	if len(directives) == 0 {
 		directives = append(directives, "ShellCompDirectiveDefault")
 	}
 
-	if d > ShellCompDirectiveError+ShellCompDirectiveNoSpace+ShellCompDirectiveNoFileComp {
+	if d > ShellCompDirectiveError+ShellCompDirectiveNoSpace+ShellCompDirectiveNoFileComp+ShellCompDirectiveKeepOrder {
 		return fmt.Sprintf("ERROR: unexpected ShellCompDirective value: %d", d)
 	}
 	return strings.Join(directives, ", ")
@@ -82,7 +255,7 @@ func (d ShellCompDirective) string() string {
 func (c *Command) initCompleteCmd(args []string) {
 	completeCmd := &Command{
 		Use:                   fmt.Sprintf("%s [command-line]", ShellCompRequestCmd),
-		Aliases:               []string{ShellCompNoDescRequestCmd},
+		Aliases:               []string{ShellCompNoDescRequestCmd, ShellCompJSONRequestCmd},
 		DisableFlagsInUseLine: true,
 		Hidden:                true,
 		DisableFlagParsing:    true,
@@ -92,6 +265,7 @@ func (c *Command) initCompleteCmd(args []string) {
 			"to request completion choices for the specified command-line.", ShellCompRequestCmd),
 		Run: func(cmd *Command, args []string) {
 			finalCmd, completions, directive, err := cmd.getCompletions(args)
+			completions, directive = finalCmd.processCompletions(completions, directive)
 			if err != nil {
 				CompErrorln(err.Error())
 				// Keep going for multiple reasons:
@@ -99,8 +273,28 @@ func (c *Command) initCompleteCmd(args []string) {
 				// 2- Even without completions, we need to print the directive
 			}
 
+			if directive > ShellCompDirectiveError+ShellCompDirectiveNoSpace+ShellCompDirectiveNoFileComp+ShellCompDirectiveKeepOrder {
+				directive = ShellCompDirectiveDefault
+			}
+
+			if cmd.CalledAs() == ShellCompJSONRequestCmd {
+				if err := writeCompletionsJSON(finalCmd.OutOrStdout(), completions, directive); err != nil {
+					CompErrorln(err.Error())
+				}
+				fmt.Fprintf(finalCmd.ErrOrStderr(), "Completion ended with directive: %s\n", directive.string())
+				return
+			}
+
 			noDescriptions := (cmd.CalledAs() == ShellCompNoDescRequestCmd)
 			for _, comp := range completions {
+				if isCompletionPlaceholder(comp) {
+					// Placeholders are made only of a description, so they are
+					// printed as-is even when descriptions are not requested.
+					// The completion scripts display them as hints but never
+					// insert them on the command-line.
+					fmt.Fprintln(finalCmd.OutOrStdout(), comp)
+					continue
+				}
 				if noDescriptions {
 					// Remove any description that may be included following a tab character.
 					comp = strings.Split(comp, "\t")[0]
@@ -109,10 +303,6 @@ func (c *Command) initCompleteCmd(args []string) {
 				fmt.Fprintln(finalCmd.OutOrStdout(), comp)
 			}
 
-			if directive > ShellCompDirectiveError+ShellCompDirectiveNoSpace+ShellCompDirectiveNoFileComp {
-				directive = ShellCompDirectiveDefault
-			}
-
 			// As the last printout, print the completion directive for the completion script to parse.
 			// The directive integer must be that last character following a single colon (:).
 			// The completion script expects :<directive>
@@ -135,6 +325,122 @@ func (c *Command) initCompleteCmd(args []string) {
 	}
 }
 
+// Completion is a completion choice, as returned by CompletionFor.
+type Completion struct {
+	// Value is the text inserted on the command-line. It is empty for placeholders.
+	Value string `json:"value"`
+	// Description is the description shown next to the value, if any.
+	Description string `json:"description,omitempty"`
+}
+
+// newCompletion splits comp, as returned by the completion functions, into its value
+// and its description.
+func newCompletion(comp string) Completion {
+	parts := strings.SplitN(comp, "\t", 2)
+	completion := Completion{Value: parts[0]}
+	if len(parts) > 1 {
+		completion.Description = parts[1]
+	}
+	return completion
+}
+
+// writeCompletionsJSON writes the completion choices and the directive to w as a JSON
+// object, for the editors requesting them with ShellCompJSONRequestCmd, e.g.
+// {"completions":[{"value":"pods","description":"List the pods"}],"directive":4}
+func writeCompletionsJSON(w io.Writer, comps []string, directive ShellCompDirective) error {
+	result := struct {
+		Completions []Completion       `json:"completions"`
+		Directive   ShellCompDirective `json:"directive"`
+	}{
+		Completions: make([]Completion, 0, len(comps)),
+		Directive:   directive,
+	}
+	for _, comp := range comps {
+		result.Completions = append(result.Completions, newCompletion(comp))
+	}
+	return json.NewEncoder(w).Encode(&result)
+}
+
+// ArgsCompletionSource returns the command providing the dynamic completion of the
+// non-flag arguments of c: c itself if it sets ValidArgsFunction, else its nearest
+// ancestor setting PersistentValidArgsFunction, unless c sets ValidArgs. It returns
+// nil if the arguments of c are not completed dynamically.
+func (c *Command) ArgsCompletionSource() *Command {
+	source, _ := c.argsCompletion()
+	return source
+}
+
+// argsCompletion returns the command providing the dynamic completion of the non-flag
+// arguments of c, as ArgsCompletionSource, along with its completion function.
+func (c *Command) argsCompletion() (*Command, func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective)) {
+	if c.ValidArgsFunction != nil {
+		return c, c.ValidArgsFunction
+	}
+	if len(c.ValidArgs) > 0 {
+		return nil, nil
+	}
+	for p := c; p != nil; p = p.Parent() {
+		if p.PersistentValidArgsFunction != nil {
+			return p, p.PersistentValidArgsFunction
+		}
+	}
+	return nil, nil
+}
+
+// CompletionFor returns the completion choices for toComplete following args on the
+// command-line, where args are the arguments given to c, e.g. the subcommands and flags
+// already typed. It runs the same logic as the shell completion scripts, including
+// ValidArgsFunction and the registered flag completion functions, which allows testing
+// them without parsing the output of the hidden completion command.
+func (c *Command) CompletionFor(args []string, toComplete string) ([]Completion, ShellCompDirective, error) {
+	var fullArgs []string
+	for p := c; p.HasParent(); p = p.Parent() {
+		fullArgs = append([]string{p.Name()}, fullArgs...)
+	}
+	fullArgs = append(fullArgs, args...)
+	fullArgs = append(fullArgs, toComplete)
+
+	// Completing parses the flags already typed, which must not stay set.
+	restoreFlags := saveFlags(c.Root())
+	finalCmd, comps, directive, err := c.Root().getCompletions(fullArgs)
+	restoreFlags()
+	comps, directive = finalCmd.processCompletions(comps, directive)
+	completions := make([]Completion, 0, len(comps))
+	for _, comp := range comps {
+		completions = append(completions, newCompletion(comp))
+	}
+	return completions, directive, err
+}
+
+// SetCompletionResultHook sets a function post-processing the completion choices of the
+// command and its descendants, e.g. to deduplicate or annotate them, before they are
+// returned to the shell. It receives the command being completed, the choices, sorted
+// if CompletionOptions.SortResults is set, and the directive, and returns those to use
+// instead. The hook of the nearest ancestor is used for the commands without one.
+func (c *Command) SetCompletionResultHook(f func(cmd *Command, comps []string, directive ShellCompDirective) ([]string, ShellCompDirective)) {
+	c.completionResultHook = f
+}
+
+// processCompletions sorts the completion choices of c, then applies the completion
+// result hook of c or of its nearest ancestor having one.
+func (c *Command) processCompletions(completions []string, directive ShellCompDirective) ([]string, ShellCompDirective) {
+	sortCompletions(c, completions, directive)
+	for p := c; p != nil; p = p.Parent() {
+		if p.completionResultHook != nil {
+			return p.completionResultHook(c, completions, directive)
+		}
+	}
+	return completions, directive
+}
+
+// sortCompletions sorts the completions in place if the root command of cmd has
+// CompletionOptions.SortResults set, unless directive asks to keep their order.
+func sortCompletions(cmd *Command, completions []string, directive ShellCompDirective) {
+	if cmd.Root().CompletionOptions.SortResults && directive&ShellCompDirectiveKeepOrder == 0 {
+		sort.Strings(completions)
+	}
+}
+
 func (c *Command) getCompletions(args []string) (*Command, []string, ShellCompDirective, error) {
 	var completions []string
 
@@ -150,17 +456,39 @@ func (c *Command) getCompletions(args []string) (*Command, []string, ShellCompDi
 		return c, completions, ShellCompDirectiveDefault, fmt.Errorf("Unable to find a command for arguments: %v", trimmedArgs)
 	}
 
+	// The help and version flags are only added when a command is executed,
+	// which is not the case when doing completion; add them now so they are
+	// part of the completions like any other flag.
+	finalCmd.InitDefaultHelpFlag()
+	finalCmd.InitDefaultVersionFlag()
+
 	// When doing completion of a flag name, as soon as an argument starts with
 	// a '-' we know it is a flag.  We cannot use isFlagArg() here as it requires
 	// the flag to be complete
 	if len(toComplete) > 0 && toComplete[0] == '-' && !strings.Contains(toComplete, "=") {
 		// We are completing a flag name
-		finalCmd.NonInheritedFlags().VisitAll(func(flag *pflag.Flag) {
-			completions = append(completions, getFlagNameCompletions(flag, toComplete)...)
-		})
-		finalCmd.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
-			completions = append(completions, getFlagNameCompletions(flag, toComplete)...)
-		})
+		var conflicts map[string]bool
+		if !finalCmd.DisableFlagParsing {
+			// Parse the flags already typed to leave out those conflicting with them.
+			// A parsing error only means that fewer flags are known to be set.
+			_ = finalCmd.ParseFlags(finalArgs)
+			conflicts = conflictingFlags(finalCmd.Flags())
+		}
+		// The flags already set are listed last, as they are less likely to be wanted again.
+		var setCompletions []string
+		addFlag := func(flag *pflag.Flag) {
+			if conflicts[flag.Name] {
+				return
+			}
+			if flag.Changed {
+				setCompletions = append(setCompletions, getFlagNameCompletions(flag, toComplete)...)
+			} else {
+				completions = append(completions, getFlagNameCompletions(flag, toComplete)...)
+			}
+		}
+		finalCmd.NonInheritedFlags().VisitAll(addFlag)
+		finalCmd.InheritedFlags().VisitAll(addFlag)
+		completions = append(completions, setCompletions...)
 
 		directive := ShellCompDirectiveDefault
 		if len(completions) > 0 {
@@ -225,7 +553,7 @@ func (c *Command) getCompletions(args []string) (*Command, []string, ShellCompDi
 	if flag != nil {
 		completionFn = flagCompletionFunctions[flag]
 	} else {
-		completionFn = finalCmd.ValidArgsFunction
+		_, completionFn = finalCmd.argsCompletion()
 	}
 	if completionFn == nil {
 		// Go custom completion not supported/needed for this flag or command
@@ -244,21 +572,22 @@ func getFlagNameCompletions(flag *pflag.Flag, toComplete string) []string {
 	}
 
 	var completions []string
+	description := flagCompletionDescription(flag)
 	flagName := "--" + flag.Name
 	if strings.HasPrefix(flagName, toComplete) {
 		// Flag without the =
-		completions = append(completions, fmt.Sprintf("%s\t%s", flagName, flag.Usage))
+		completions = append(completions, fmt.Sprintf("%s\t%s", flagName, description))
 
 		if len(flag.NoOptDefVal) == 0 {
 			// Flag requires a value, so it can be suffixed with =
 			flagName += "="
-			completions = append(completions, fmt.Sprintf("%s\t%s", flagName, flag.Usage))
+			completions = append(completions, fmt.Sprintf("%s\t%s", flagName, description))
 		}
 	}
 
 	flagName = "-" + flag.Shorthand
 	if len(flag.Shorthand) > 0 && strings.HasPrefix(flagName, toComplete) {
-		completions = append(completions, fmt.Sprintf("%s\t%s", flagName, flag.Usage))
+		completions = append(completions, fmt.Sprintf("%s\t%s", flagName, description))
 	}
 
 	return completions
@@ -301,7 +630,7 @@ func checkIfFlagCompletion(finalCmd *Command, args []string, lastArg string) (*p
 		return nil, trimmedArgs, lastArg, nil
 	}
 
-	flag := findFlag(finalCmd, flagName)
+	flag := finalCmd.LookupFlag(flagName)
 	if flag == nil {
 		// Flag not supported by this command, nothing to complete
 		err := fmt.Errorf("Subcommand '%s' does not support flag '%s'", finalCmd.Name(), flagName)
@@ -321,25 +650,6 @@ func checkIfFlagCompletion(finalCmd *Command, args []string, lastArg string) (*p
 	return flag, trimmedArgs, lastArg, nil
 }
 
-func findFlag(cmd *Command, name string) *pflag.Flag {
-	flagSet := cmd.Flags()
-	if len(name) == 1 {
-		// First convert the short flag into a long flag
-		// as the cmd.Flag() search only accepts long flags
-		if short := flagSet.ShorthandLookup(name); short != nil {
-			name = short.Name
-		} else {
-			set := cmd.InheritedFlags()
-			if short = set.ShorthandLookup(name); short != nil {
-				name = short.Name
-			} else {
-				return nil
-			}
-		}
-	}
-	return cmd.Flag(name)
-}
-
 // CompDebug prints the specified string to the same file as where the
 // completion script prints its logs.
 // Note that completion printouts should never be on stdout as they would
@@ -2,6 +2,9 @@ package cobra
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -629,3 +632,531 @@ func TestFlagCompletionInGoWithDesc(t *testing.T) {
 		t.Errorf("expected: %q, got: %q", expected, output)
 	}
 }
+
+func TestValidArgsFuncPlaceholder(t *testing.T) {
+	rootCmd := &Command{
+		Use: "root",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+			return []string{"known\tA known value", CompletionPlaceholder("<name>", "The resource name")}, ShellCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+
+	// The placeholder keeps its description when descriptions are requested
+	output, err := executeCommand(rootCmd, ShellCompRequestCmd, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"known\tA known value",
+		"\t<name>  The resource name",
+		":4",
+		"Completion ended with directive: ShellCompDirectiveNoFileComp", ""}, "\n")
+
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	// The placeholder is not stripped to an empty value when descriptions are not requested
+	output, err = executeCommand(rootCmd, ShellCompNoDescRequestCmd, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected = strings.Join([]string{
+		"known",
+		"\t<name>  The resource name",
+		":4",
+		"Completion ended with directive: ShellCompDirectiveNoFileComp", ""}, "\n")
+
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestFlagCompletionDescription(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().StringP("output", "o", "", "the output format, one of json|yaml|table; table is the default and prints a human readable summary")
+	rootCmd.Flags().Bool("quiet", false, "do not print anything")
+	if err := rootCmd.SetFlagCompletionDescription("output", "output format"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := rootCmd.SetFlagCompletionDescription("unknown", "unknown flag"); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+
+	output, err := executeCommand(rootCmd, ShellCompRequestCmd, "-")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"--help\thelp for root",
+		"-h\thelp for root",
+		"--output\toutput format",
+		"--output=\toutput format",
+		"-o\toutput format",
+		"--quiet\tdo not print anything",
+		":0",
+		"Completion ended with directive: ShellCompDirectiveDefault", ""}, "\n")
+
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	// The usage is still used in the help output
+	output, err = executeCommand(rootCmd, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "one of json|yaml|table")
+}
+
+func TestHelpAndVersionFlagCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "root", Version: "1.0.0", Run: emptyRun}
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+
+	output, err := executeCommand(rootCmd, ShellCompNoDescRequestCmd, "--h")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"--help",
+		":0",
+		"Completion ended with directive: ShellCompDirectiveDefault", ""}, "\n")
+
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	output, err = executeCommand(rootCmd, ShellCompNoDescRequestCmd, "--v")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected = strings.Join([]string{
+		"--version",
+		":0",
+		"Completion ended with directive: ShellCompDirectiveDefault", ""}, "\n")
+
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	// The help flag is also completed for subcommands
+	output, err = executeCommand(rootCmd, ShellCompNoDescRequestCmd, "child", "-")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected = strings.Join([]string{
+		"--help",
+		"-h",
+		":0",
+		"Completion ended with directive: ShellCompDirectiveDefault", ""}, "\n")
+
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestCompoundValueFlagCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().String("tags", "", "tags")
+	rootCmd.Flags().String("set", "", "setting")
+	_ = rootCmd.RegisterFlagCompletionFunc("tags", CommaListCompletion([]string{"alpha", "beta", "gamma"}))
+	_ = rootCmd.RegisterFlagCompletionFunc("set", KeyValueCompletion([]string{"color", "size"}, func(key string) []string {
+		if key == "color" {
+			return []string{"red", "green"}
+		}
+		return nil
+	}))
+
+	testcases := []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			args:     []string{"--tags", ""},
+			expected: []string{"alpha", "beta", "gamma", ":6"},
+		},
+		{
+			args:     []string{"--tags", "beta,"},
+			expected: []string{"beta,alpha", "beta,gamma", ":6"},
+		},
+		{
+			args:     []string{"--tags", "beta,alpha,g"},
+			expected: []string{"beta,alpha,gamma", ":6"},
+		},
+		{
+			args:     []string{"--set", "c"},
+			expected: []string{"color=", ":6"},
+		},
+		{
+			args:     []string{"--set", "color="},
+			expected: []string{"color=red", "color=green", ":4"},
+		},
+		{
+			args:     []string{"--set", "color=g"},
+			expected: []string{"color=green", ":4"},
+		},
+		{
+			args:     []string{"--set", "size="},
+			expected: []string{":4"},
+		},
+	}
+
+	for _, tc := range testcases {
+		output, err := executeCommand(rootCmd, append([]string{ShellCompNoDescRequestCmd}, tc.args...)...)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		expected := strings.Join(tc.expected, "\n") + "\n"
+		if !strings.HasPrefix(output, expected) {
+			t.Errorf("%v: expected: %q, got: %q", tc.args, expected, output)
+		}
+	}
+}
+
+func TestCompoundValueCompletionKeepsPlaceholders(t *testing.T) {
+	f := CompoundValueCompletion(":", func(cmd *Command, args []string, typed, toComplete string) ([]string, ShellCompDirective) {
+		if typed != "a:b:" || toComplete != "c" {
+			t.Errorf("Unexpected typed portion %q and segment %q", typed, toComplete)
+		}
+		return []string{"cc", CompletionPlaceholder("<part>", "a part")}, ShellCompDirectiveNoSpace
+	})
+
+	comps, directive := f(nil, nil, "a:b:c")
+	expected := []string{"a:b:cc", CompletionPlaceholder("<part>", "a part")}
+	if strings.Join(comps, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected: %q, got: %q", expected, comps)
+	}
+	if directive != ShellCompDirectiveNoSpace {
+		t.Errorf("expected directive %d, got %d", ShellCompDirectiveNoSpace, directive)
+	}
+}
+
+func TestCompleteJSONCmd(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{
+		Use:               "childCmd",
+		ValidArgsFunction: validArgsFunc,
+		Run:               emptyRun,
+	}
+	rootCmd.AddCommand(childCmd)
+
+	output, err := executeCommand(rootCmd, ShellCompJSONRequestCmd, "childCmd", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// The JSON object is on the first line, followed by the debug message on stderr.
+	var result struct {
+		Completions []Completion       `json:"completions"`
+		Directive   ShellCompDirective `json:"directive"`
+	}
+	if err := json.Unmarshal([]byte(strings.SplitN(output, "\n", 2)[0]), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, output)
+	}
+
+	expected := []Completion{
+		{Value: "one", Description: "The first"},
+		{Value: "two", Description: "The second"},
+	}
+	if !reflect.DeepEqual(result.Completions, expected) {
+		t.Errorf("expected: %v, got: %v", expected, result.Completions)
+	}
+	if result.Directive != ShellCompDirectiveDefault {
+		t.Errorf("expected directive %d, got %d", ShellCompDirectiveDefault, result.Directive)
+	}
+	checkStringOmits(t, output, "one\tThe first")
+}
+
+func TestCompleteJSONCmdNoCompletions(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "childCmd", Run: emptyRun})
+
+	output, err := executeCommand(rootCmd, ShellCompJSONRequestCmd, "childCmd", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	checkStringContains(t, output, `{"completions":[],"directive":0}`+"\n")
+}
+
+func TestCompletionFor(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{
+		Use:   "child",
+		Short: "The child command",
+		Run:   emptyRun,
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+			return []string{"arg" + fmt.Sprint(len(args))}, ShellCompDirectiveNoFileComp
+		},
+	}
+	rootCmd.AddCommand(childCmd, &Command{Use: "other", Short: "Another command", Run: emptyRun})
+	childCmd.Flags().String("output", "", "output format")
+	_ = childCmd.RegisterFlagCompletionFunc("output", func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+		return []string{"json\tJSON output", "yaml\tYAML output"}, ShellCompDirectiveNoSpace
+	})
+
+	testcases := []struct {
+		cmd        *Command
+		args       []string
+		toComplete string
+		expected   []Completion
+		directive  ShellCompDirective
+	}{
+		{
+			// Subcommand completion
+			cmd:        rootCmd,
+			toComplete: "",
+			expected: []Completion{
+				{Value: "child", Description: "The child command"},
+				{Value: "other", Description: "Another command"},
+			},
+			directive: ShellCompDirectiveDefault,
+		},
+		{
+			// Flag name completion
+			cmd:        rootCmd,
+			args:       []string{"child"},
+			toComplete: "--o",
+			expected: []Completion{
+				{Value: "--output", Description: "output format"},
+				{Value: "--output=", Description: "output format"},
+			},
+			directive: ShellCompDirectiveDefault,
+		},
+		{
+			// Registered flag completion function
+			cmd:        rootCmd,
+			args:       []string{"child", "--output"},
+			toComplete: "",
+			expected: []Completion{
+				{Value: "json", Description: "JSON output"},
+				{Value: "yaml", Description: "YAML output"},
+			},
+			directive: ShellCompDirectiveNoSpace,
+		},
+		{
+			// Arguments are relative to the command
+			cmd:        childCmd,
+			args:       []string{"--output", "json", "first"},
+			toComplete: "",
+			expected:   []Completion{{Value: "arg1"}},
+			directive:  ShellCompDirectiveNoFileComp,
+		},
+	}
+
+	for _, tc := range testcases {
+		completions, directive, err := tc.cmd.CompletionFor(tc.args, tc.toComplete)
+		if err != nil {
+			t.Errorf("%v %q: unexpected error: %v", tc.args, tc.toComplete, err)
+		}
+		if !reflect.DeepEqual(completions, tc.expected) {
+			t.Errorf("%v %q: expected: %v, got: %v", tc.args, tc.toComplete, tc.expected, completions)
+		}
+		if directive != tc.directive {
+			t.Errorf("%v %q: expected directive %s, got %s", tc.args, tc.toComplete, tc.directive.string(), directive.string())
+		}
+	}
+
+	if _, _, err := rootCmd.CompletionFor([]string{"unknown"}, ""); err == nil {
+		t.Error("Expected an error for an unknown command")
+	}
+}
+
+func TestCompletionSortResults(t *testing.T) {
+	directive := ShellCompDirectiveNoFileComp
+	rootCmd := &Command{
+		Use: "root",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+			return []string{"charlie", "alpha", "bravo"}, directive
+		},
+		Run: emptyRun,
+	}
+
+	// The order of the completion function is kept by default.
+	output, err := executeCommand(rootCmd, ShellCompNoDescRequestCmd, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"charlie",
+		"alpha",
+		"bravo",
+		":4",
+		"Completion ended with directive: ShellCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	rootCmd.CompletionOptions.SortResults = true
+	output, err = executeCommand(rootCmd, ShellCompNoDescRequestCmd, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"alpha",
+		"bravo",
+		"charlie",
+		":4",
+		"Completion ended with directive: ShellCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	// ShellCompDirectiveKeepOrder disables the sorting.
+	directive = ShellCompDirectiveNoFileComp | ShellCompDirectiveKeepOrder
+	output, err = executeCommand(rootCmd, ShellCompNoDescRequestCmd, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"charlie",
+		"alpha",
+		"bravo",
+		":36",
+		"Completion ended with directive: ShellCompDirectiveNoFileComp, ShellCompDirectiveKeepOrder", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestRegisterFlagCompletionFuncTwice(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().String("output", "", "output format")
+	first := func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+		return []string{"first"}, ShellCompDirectiveDefault
+	}
+	second := func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+		return []string{"second"}, ShellCompDirectiveDefault
+	}
+
+	if err := rootCmd.RegisterFlagCompletionFunc("output", first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := rootCmd.RegisterFlagCompletionFunc("output", second)
+	if err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("Expected an error when registering a second function, got: %v", err)
+	}
+	completions, _, _ := rootCmd.CompletionFor([]string{"--output"}, "")
+	if len(completions) != 1 || completions[0].Value != "first" {
+		t.Errorf("Expected the first function to be kept, got: %v", completions)
+	}
+
+	if err := rootCmd.ReplaceFlagCompletionFunc("output", second); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	completions, _, _ = rootCmd.CompletionFor([]string{"--output"}, "")
+	if len(completions) != 1 || completions[0].Value != "second" {
+		t.Errorf("Expected the function to be replaced, got: %v", completions)
+	}
+
+	if err := rootCmd.ReplaceFlagCompletionFunc("missing", second); err == nil {
+		t.Error("Expected an error for a missing flag")
+	}
+}
+
+func TestFlagCompletionFuncs(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+	rootCmd.PersistentFlags().String("context", "", "")
+	childCmd.Flags().String("namespace", "", "")
+	childCmd.Flags().String("output", "", "")
+
+	completion := func(*Command, []string, string) ([]string, ShellCompDirective) {
+		return nil, ShellCompDirectiveNoFileComp
+	}
+	_ = rootCmd.RegisterFlagCompletionFunc("context", completion)
+	_ = childCmd.RegisterFlagCompletionFunc("namespace", completion)
+
+	expected := map[string]bool{"context": true, "namespace": true}
+	if got := childCmd.FlagCompletionFuncs(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+	expected = map[string]bool{"context": true}
+	if got := rootCmd.FlagCompletionFuncs(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestCompletionResultHook(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{
+		Use: "child",
+		ValidArgsFunction: func(*Command, []string, string) ([]string, ShellCompDirective) {
+			return []string{"prod", "dev", "prod"}, ShellCompDirectiveDefault
+		},
+		Run: emptyRun,
+	}
+	rootCmd.AddCommand(childCmd)
+	rootCmd.SetCompletionResultHook(func(cmd *Command, comps []string, directive ShellCompDirective) ([]string, ShellCompDirective) {
+		var unique []string
+		for _, comp := range comps {
+			if !stringInSlice(comp, unique) {
+				unique = append(unique, comp)
+			}
+		}
+		return unique, directive | ShellCompDirectiveNoFileComp
+	})
+
+	output, err := executeCommand(rootCmd, ShellCompNoDescRequestCmd, "child", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"prod",
+		"dev",
+		":4",
+		"Completion ended with directive: ShellCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	comps, directive, err := childCmd.CompletionFor(nil, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(comps) != 2 || directive != ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected the hook to apply to CompletionFor, got %v and %s", comps, directive.string())
+	}
+}
+
+func TestPersistentValidArgsFunction(t *testing.T) {
+	rootCmd := &Command{
+		Use: "root",
+		PersistentValidArgsFunction: func(*Command, []string, string) ([]string, ShellCompDirective) {
+			return []string{"pod-a", "pod-b"}, ShellCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	getCmd := &Command{Use: "get", Run: emptyRun}
+	listCmd := &Command{Use: "list", ValidArgs: []string{"pods", "nodes"}, Run: emptyRun}
+	rootCmd.AddCommand(getCmd, listCmd)
+
+	if source := getCmd.ArgsCompletionSource(); source != rootCmd {
+		t.Errorf("Expected the root command as source, got %v", source)
+	}
+	if source := listCmd.ArgsCompletionSource(); source != nil {
+		t.Errorf("Expected no source for a command with ValidArgs, got %v", source.Name())
+	}
+
+	output, err := executeCommand(rootCmd, ShellCompNoDescRequestCmd, "get", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "pod-a\npod-b\n:4\n")
+
+	output, err = executeCommand(rootCmd, ShellCompNoDescRequestCmd, "list", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringOmits(t, output, "pod-a")
+}
@@ -0,0 +1,128 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// RemovedInAnnotation is the annotation of a deprecated command, or flag, holding
+// the version in which it will be removed, e.g. "v3.0", shown by GenDeprecations.
+const RemovedInAnnotation = "removed_in"
+
+// deprecation describes an item of the command tree slated for removal.
+type deprecation struct {
+	item      string
+	kind      string
+	message   string
+	removedIn string
+	cmd       *cobra.Command
+}
+
+// GenDeprecations writes in Markdown a table of the deprecated commands, aliases,
+// flags and flag shorthands of the tree of cmd, with their deprecation messages,
+// the versions in which they will be removed, from their RemovedInAnnotation, and
+// links to the pages of their commands. As the Markdown generators only write the pages
+// of the deprecated commands with IncludeDeprecated, the deprecated commands link to the
+// pages of their parents, and the commands below them are not listed. The links are
+// built like those of GenMarkdownCustom, through linkHandler. Nothing is written if
+// nothing is deprecated.
+func GenDeprecations(cmd *cobra.Command, w io.Writer, linkHandler func(string) string) error {
+	if linkHandler == nil {
+		linkHandler = func(s string) string { return s }
+	}
+
+	deprecations := collectDeprecations(cmd, nil)
+	if len(deprecations) == 0 {
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("| Item | Type | Message | Removed in | Page |\n")
+	buf.WriteString("|------|------|---------|------------|------|\n")
+	for _, d := range deprecations {
+		page := d.cmd
+		if len(page.Deprecated) > 0 && page.HasParent() {
+			page = page.Parent()
+		}
+		path := page.CommandPath()
+		buf.WriteString(fmt.Sprintf("| %s | %s | %s | %s | [%s](%s) |\n",
+			d.item, d.kind, escapeTableCell(d.message), escapeTableCell(d.removedIn),
+			path, linkHandler(mdDefaultLinkHandler(path))))
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// collectDeprecations appends the deprecated items of the tree of cmd to deprecations,
+// skipping the hidden commands and flags, as they are not documented. Deprecated
+// flags are kept, as pflag hides them.
+func collectDeprecations(cmd *cobra.Command, deprecations []deprecation) []deprecation {
+	path := cmd.CommandPath()
+	if len(cmd.Deprecated) > 0 {
+		deprecations = append(deprecations, deprecation{
+			item:      "`" + path + "`",
+			kind:      "command",
+			message:   cmd.Deprecated,
+			removedIn: cmd.Annotations[RemovedInAnnotation],
+			cmd:       cmd,
+		})
+		// The whole subtree of the command goes with it.
+		return deprecations
+	}
+	for _, alias := range cmd.Aliases {
+		if message, ok := cmd.AliasDeprecation(alias); ok {
+			deprecations = append(deprecations, deprecation{
+				item:    "`" + alias + "` of `" + path + "`",
+				kind:    "alias",
+				message: message,
+				cmd:     cmd,
+			})
+		}
+	}
+
+	cmd.NonInheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden && len(flag.Deprecated) == 0 {
+			return
+		}
+		var removedIn string
+		if values := flag.Annotations[RemovedInAnnotation]; len(values) > 0 {
+			removedIn = values[0]
+		}
+		if len(flag.Deprecated) > 0 {
+			deprecations = append(deprecations, deprecation{
+				item:      "`--" + flag.Name + "` of `" + path + "`",
+				kind:      "flag",
+				message:   flag.Deprecated,
+				removedIn: removedIn,
+				cmd:       cmd,
+			})
+		} else if len(flag.ShorthandDeprecated) > 0 {
+			deprecations = append(deprecations, deprecation{
+				item:      "`-" + flag.Shorthand + "` of `" + path + "`",
+				kind:      "flag shorthand",
+				message:   flag.ShorthandDeprecated,
+				removedIn: removedIn,
+				cmd:       cmd,
+			})
+		}
+	})
+
+	for _, c := range cmd.Commands() {
+		if c.Hidden || c.IsAdditionalHelpTopicCommand() || c.Name() == "help" {
+			continue
+		}
+		deprecations = collectDeprecations(c, deprecations)
+	}
+	return deprecations
+}
+
+// escapeTableCell escapes the pipes and line breaks of s for use in a Markdown table cell.
+func escapeTableCell(s string) string {
+	s = strings.Replace(s, "|", "\\|", -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
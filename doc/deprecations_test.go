@@ -0,0 +1,54 @@
+package doc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenDeprecations(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{Use: "get", Aliases: []string{"fetch"}, Run: emptyRun}
+	get.Flags().String("format", "", "")
+	get.Flags().StringP("output", "o", "", "")
+	_ = get.Flags().MarkDeprecated("format", "use --output instead")
+	_ = get.Flags().SetAnnotation("format", RemovedInAnnotation, []string{"v3.0"})
+	_ = get.Flags().MarkShorthandDeprecated("output", "use --output instead")
+	_ = get.MarkAliasDeprecated("fetch", "use get instead")
+	old := &cobra.Command{
+		Use:         "old",
+		Deprecated:  "use get | list instead",
+		Annotations: map[string]string{RemovedInAnnotation: "v2.5"},
+		Run:         emptyRun,
+	}
+	old.AddCommand(&cobra.Command{Use: "older", Deprecated: "use get instead", Run: emptyRun})
+	root.AddCommand(get, old)
+
+	buf := new(bytes.Buffer)
+	if err := GenDeprecations(root, buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "| Item | Type | Message | Removed in | Page |\n")
+	checkStringContains(t, output, "| `fetch` of `app get` | alias | use get instead |  | [app get](app_get.md) |\n")
+	checkStringContains(t, output, "| `--format` of `app get` | flag | use --output instead | v3.0 | [app get](app_get.md) |\n")
+	checkStringContains(t, output, "| `-o` of `app get` | flag shorthand | use --output instead |  | [app get](app_get.md) |\n")
+	checkStringContains(t, output, "| `app old` | command | use get \\| list instead | v2.5 | [app](app.md) |\n")
+	checkStringOmits(t, output, "older")
+	checkStringOmits(t, output, "app_old.md")
+}
+
+func TestGenDeprecationsEmpty(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	root.AddCommand(&cobra.Command{Use: "get", Run: emptyRun})
+
+	buf := new(bytes.Buffer)
+	if err := GenDeprecations(root, buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("Expected no output, got %q", buf.String())
+	}
+}
@@ -0,0 +1,260 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// GenCLIDiff writes in Markdown the changes between two versions of a command tree,
+// e.g. the trees of the previous and the current release: added and removed commands,
+// and for the commands found in both trees, added, removed and changed flags (type or
+// default value) and changed descriptions. Nothing is written if the trees are identical.
+func GenCLIDiff(oldRoot, newRoot *cobra.Command, w io.Writer) error {
+	oldCmds := commandsByPath(oldRoot)
+	newCmds := commandsByPath(newRoot)
+
+	buf := new(bytes.Buffer)
+
+	var added, removed, common []string
+	for _, path := range sortedPaths(newCmds) {
+		if _, ok := oldCmds[path]; ok {
+			common = append(common, path)
+		} else {
+			added = append(added, path)
+		}
+	}
+	for _, path := range sortedPaths(oldCmds) {
+		if _, ok := newCmds[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+
+	if len(added) > 0 {
+		buf.WriteString("## Added commands\n\n")
+		for _, path := range added {
+			buf.WriteString(fmt.Sprintf("* `%s`: %s\n", path, newCmds[path].Short))
+		}
+		buf.WriteString("\n")
+	}
+
+	if len(removed) > 0 {
+		buf.WriteString("## Removed commands\n\n")
+		for _, path := range removed {
+			buf.WriteString(fmt.Sprintf("* `%s`\n", path))
+		}
+		buf.WriteString("\n")
+	}
+
+	changedBuf := new(bytes.Buffer)
+	for _, path := range common {
+		changes := diffCommand(oldCmds[path], newCmds[path])
+		if len(changes) == 0 {
+			continue
+		}
+		changedBuf.WriteString("### " + path + "\n\n")
+		for _, change := range changes {
+			changedBuf.WriteString("* " + change + "\n")
+		}
+		changedBuf.WriteString("\n")
+	}
+	if changedBuf.Len() > 0 {
+		buf.WriteString("## Changed commands\n\n")
+		changedBuf.WriteTo(buf)
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// GenFlagChangelog writes in Markdown the changes of the flags between two versions of
+// a command tree, e.g. the trees of the previous and the current release, as they are
+// those breaking scripts: a table per command found in both trees, listing its added
+// and removed flags, and the flags whose type or default value changed, or which were
+// deprecated. The commands whose flags did not change are omitted, and nothing is
+// written if no flag changed.
+func GenFlagChangelog(oldRoot, newRoot *cobra.Command, w io.Writer) error {
+	oldCmds := commandsByPath(oldRoot)
+	newCmds := commandsByPath(newRoot)
+
+	buf := new(bytes.Buffer)
+	for _, path := range sortedPaths(newCmds) {
+		oldCmd, ok := oldCmds[path]
+		if !ok {
+			continue
+		}
+		changes := diffFlags(oldCmd, newCmds[path])
+		if len(changes) == 0 {
+			continue
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("### " + path + "\n\n")
+		buf.WriteString("| Flag | Change | Before | After |\n")
+		buf.WriteString("|------|--------|--------|-------|\n")
+		for _, change := range changes {
+			for _, row := range change.rows() {
+				buf.WriteString(fmt.Sprintf("| `--%s` | %s |\n", change.Name, strings.Join(row, " | ")))
+			}
+		}
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// commandsByPath returns the commands of the tree, except the hidden ones and the help
+// command, indexed by path.
+func commandsByPath(cmd *cobra.Command) map[string]*cobra.Command {
+	cmds := map[string]*cobra.Command{}
+	var visit func(*cobra.Command)
+	visit = func(c *cobra.Command) {
+		cmds[c.CommandPath()] = c
+		for _, child := range c.Commands() {
+			if child.Hidden || child.Name() == "help" && !child.IsAvailableCommand() {
+				continue
+			}
+			visit(child)
+		}
+	}
+	visit(cmd)
+	return cmds
+}
+
+func sortedPaths(cmds map[string]*cobra.Command) []string {
+	paths := make([]string, 0, len(cmds))
+	for path := range cmds {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// diffCommand describes the changes of the descriptions and flags of a command.
+func diffCommand(oldCmd, newCmd *cobra.Command) []string {
+	var changes []string
+	if oldCmd.Short != newCmd.Short {
+		changes = append(changes, fmt.Sprintf("Changed short description: %q → %q", oldCmd.Short, newCmd.Short))
+	}
+	if oldCmd.LongText() != newCmd.LongText() {
+		changes = append(changes, "Changed long description")
+	}
+	for _, change := range diffFlags(oldCmd, newCmd) {
+		if change.Old != nil && change.New != nil && !change.TypeChanged && !change.DefaultChanged {
+			continue // only deprecated, which is reported by GenFlagChangelog
+		}
+		changes = append(changes, change.String())
+	}
+	return changes
+}
+
+// documentedFlags returns the non-inherited flags of cmd, except the hidden ones which
+// are not deprecated, indexed by name.
+func documentedFlags(cmd *cobra.Command) map[string]*pflag.Flag {
+	flags := map[string]*pflag.Flag{}
+	cmd.NonInheritedFlags().VisitAll(func(f *pflag.Flag) {
+		if !f.Hidden || len(f.Deprecated) > 0 {
+			flags[f.Name] = f
+		}
+	})
+	return flags
+}
+
+// flagChange describes how a flag changed between two versions of a command.
+type flagChange struct {
+	Name           string
+	Old            *pflag.Flag // nil when the flag was added
+	New            *pflag.Flag // nil when the flag was removed
+	TypeChanged    bool        // whether the type of an existing flag changed
+	DefaultChanged bool        // whether the default value of an existing flag changed
+	Deprecated     bool        // whether an existing flag was deprecated
+}
+
+func (c flagChange) String() string {
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("Added flag `--%s`", c.Name)
+	case c.New == nil:
+		return fmt.Sprintf("Removed flag `--%s`", c.Name)
+	}
+	var changes []string
+	if c.TypeChanged {
+		changes = append(changes, fmt.Sprintf("type `%s` → `%s`", c.Old.Value.Type(), c.New.Value.Type()))
+	}
+	if c.DefaultChanged {
+		changes = append(changes, fmt.Sprintf("default `%s` → `%s`", c.Old.DefValue, c.New.DefValue))
+	}
+	return fmt.Sprintf("Changed flag `--%s`: %s", c.Name, strings.Join(changes, ", "))
+}
+
+// rows returns the change, before and after cells of the rows of the change in the
+// table of GenFlagChangelog.
+func (c flagChange) rows() [][]string {
+	switch {
+	case c.Old == nil:
+		return [][]string{{"added", "", flagSummary(c.New)}}
+	case c.New == nil:
+		return [][]string{{"removed", flagSummary(c.Old), ""}}
+	}
+
+	var rows [][]string
+	if c.TypeChanged {
+		rows = append(rows, []string{"type changed", "`" + c.Old.Value.Type() + "`", "`" + c.New.Value.Type() + "`"})
+	}
+	if c.DefaultChanged {
+		rows = append(rows, []string{"default changed", "`" + escapeTableCell(c.Old.DefValue) + "`", "`" + escapeTableCell(c.New.DefValue) + "`"})
+	}
+	if c.Deprecated {
+		rows = append(rows, []string{"deprecated", "", escapeTableCell(c.New.Deprecated)})
+	}
+	return rows
+}
+
+// flagSummary describes the type and default value of a flag in a table cell.
+func flagSummary(f *pflag.Flag) string {
+	summary := "`" + f.Value.Type() + "`"
+	if len(f.DefValue) > 0 {
+		summary += ", default `" + escapeTableCell(f.DefValue) + "`"
+	}
+	return summary
+}
+
+// diffFlags compares the non-inherited flags of two versions of a command, except the
+// hidden ones which are not deprecated, like the other generators.
+func diffFlags(oldCmd, newCmd *cobra.Command) []flagChange {
+	oldFlags := documentedFlags(oldCmd)
+	newFlags := documentedFlags(newCmd)
+
+	var names []string
+	for name := range oldFlags {
+		names = append(names, name)
+	}
+	for name := range newFlags {
+		if _, ok := oldFlags[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []flagChange
+	for _, name := range names {
+		change := flagChange{Name: name, Old: oldFlags[name], New: newFlags[name]}
+		if change.Old != nil && change.New != nil {
+			change.TypeChanged = change.Old.Value.Type() != change.New.Value.Type()
+			change.DefaultChanged = change.Old.DefValue != change.New.DefValue
+			change.Deprecated = len(change.Old.Deprecated) == 0 && len(change.New.Deprecated) > 0
+			if !change.TypeChanged && !change.DefaultChanged && !change.Deprecated {
+				continue
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
@@ -0,0 +1,121 @@
+package doc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func diffTestTrees() (*cobra.Command, *cobra.Command) {
+	oldRoot := &cobra.Command{Use: "app", Run: emptyRun}
+	oldDeploy := &cobra.Command{Use: "deploy", Short: "Deploy the app", Run: emptyRun}
+	oldDeploy.Flags().Int("timeout", 30, "")
+	oldDeploy.Flags().Bool("legacy", false, "")
+	oldDeploy.Flags().String("env", "dev", "")
+	oldRoot.AddCommand(oldDeploy, &cobra.Command{Use: "old", Short: "Old command", Run: emptyRun})
+
+	newRoot := &cobra.Command{Use: "app", Run: emptyRun}
+	newDeploy := &cobra.Command{Use: "deploy", Short: "Deploy the application", Run: emptyRun}
+	newDeploy.Flags().Duration("timeout", 0, "")
+	newDeploy.Flags().Bool("force", false, "")
+	newDeploy.Flags().String("env", "dev", "")
+	newRoot.AddCommand(newDeploy, &cobra.Command{Use: "new", Short: "New command", Run: emptyRun})
+
+	return oldRoot, newRoot
+}
+
+func TestGenCLIDiff(t *testing.T) {
+	oldRoot, newRoot := diffTestTrees()
+
+	buf := new(bytes.Buffer)
+	if err := GenCLIDiff(oldRoot, newRoot, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "## Added commands\n\n* `app new`: New command\n")
+	checkStringContains(t, output, "## Removed commands\n\n* `app old`\n")
+	checkStringContains(t, output, "### app deploy\n")
+	checkStringContains(t, output, `* Changed short description: "Deploy the app" → "Deploy the application"`)
+	checkStringContains(t, output, "* Added flag `--force`")
+	checkStringContains(t, output, "* Removed flag `--legacy`")
+	checkStringContains(t, output, "* Changed flag `--timeout`: type `int` → `duration`, default `30` → `0s`")
+	checkStringOmits(t, output, "--env")
+
+	buf.Reset()
+	if err := GenCLIDiff(newRoot, newRoot, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for identical trees, got %q", buf.String())
+	}
+}
+
+func TestGenCLIDiffHidden(t *testing.T) {
+	oldRoot := &cobra.Command{Use: "app", Run: emptyRun}
+	oldGet := &cobra.Command{Use: "get", Run: emptyRun}
+	oldGet.Flags().String("format", "table", "")
+	oldRoot.AddCommand(oldGet)
+
+	newRoot := &cobra.Command{Use: "app", Run: emptyRun}
+	newGet := &cobra.Command{Use: "get", Run: emptyRun}
+	newGet.Flags().String("format", "table", "")
+	newGet.Flags().Bool("trace", false, "")
+	_ = newGet.Flags().MarkHidden("trace")
+	newRoot.AddCommand(newGet, &cobra.Command{Use: "debug", Hidden: true, Run: emptyRun})
+
+	buf := new(bytes.Buffer)
+	if err := GenCLIDiff(oldRoot, newRoot, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for hidden changes, got %q", buf.String())
+	}
+}
+
+func TestGenFlagChangelog(t *testing.T) {
+	oldRoot, newRoot := diffTestTrees()
+	oldRoot.AddCommand(&cobra.Command{Use: "status", Run: emptyRun})
+	newStatus := &cobra.Command{Use: "status", Run: emptyRun}
+	newStatus.Flags().String("format", "table", "")
+	newStatus.Flags().String("output", "", "")
+	_ = newStatus.Flags().MarkDeprecated("format", "use --output instead")
+	newStatus.Flags().Bool("trace", false, "")
+	_ = newStatus.Flags().MarkHidden("trace")
+	newRoot.AddCommand(newStatus)
+	oldStatus, _, _ := oldRoot.Find([]string{"status"})
+	oldStatus.Flags().String("format", "table", "")
+
+	buf := new(bytes.Buffer)
+	if err := GenFlagChangelog(oldRoot, newRoot, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "### app deploy\n\n| Flag | Change | Before | After |\n|------|--------|--------|-------|\n")
+	checkStringContains(t, output, "| `--force` | added |  | `bool`, default `false` |\n")
+	checkStringContains(t, output, "| `--legacy` | removed | `bool`, default `false` |  |\n")
+	checkStringContains(t, output, "| `--timeout` | type changed | `int` | `duration` |\n")
+	checkStringContains(t, output, "| `--timeout` | default changed | `30` | `0s` |\n")
+	checkStringContains(t, output, "### app status\n")
+	checkStringContains(t, output, "| `--format` | deprecated |  | use --output instead |\n")
+	checkStringContains(t, output, "| `--output` | added |  | `string` |\n")
+	checkStringOmits(t, output, "--env")
+	checkStringOmits(t, output, "app new")
+	checkStringOmits(t, output, "--trace")
+
+	buf.Reset()
+	if err := GenCLIDiff(oldRoot, newRoot, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "--format")
+
+	buf.Reset()
+	if err := GenFlagChangelog(newRoot, newRoot, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for identical trees, got %q", buf.String())
+	}
+}
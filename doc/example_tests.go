@@ -0,0 +1,84 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+// GenExampleTests writes the source of a Go test file of package pkgName, with one
+// test function per command of the tree of cmd whose Example invokes it. Each test
+// asserts with cobra.Command.CheckInvocation that the invocations of the command in its
+// examples find the command, and that their flags and arguments parse and validate,
+// including the flag groups and the required flags. The flags are restored after each
+// invocation.
+// The tests refer to the root command as the rootCmd variable of the package, as
+// declared by the applications created by the cobra generator.
+func GenExampleTests(cmd *cobra.Command, w io.Writer, pkgName string) error {
+	buf := new(bytes.Buffer)
+	buf.WriteString("// Code generated by GenExampleTests. DO NOT EDIT.\n\n")
+	buf.WriteString("package " + pkgName + "\n\n")
+	buf.WriteString("import \"testing\"\n")
+	genExampleTests(cmd, buf)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func genExampleTests(cmd *cobra.Command, buf *bytes.Buffer) {
+	if invocations := cmd.ExampleInvocations(); len(invocations) > 0 {
+		path := cmd.CommandPath()
+		buf.WriteString("\nfunc " + exampleTestName(path) + "(t *testing.T) {\n")
+		buf.WriteString("\tfor _, args := range [][]string{\n")
+		for _, args := range invocations {
+			buf.WriteString(fmt.Sprintf("\t\t%#v,\n", args))
+		}
+		buf.WriteString("\t} {\n")
+		buf.WriteString("\t\tcmd, err := rootCmd.CheckInvocation(args)\n")
+		buf.WriteString("\t\tif err != nil {\n")
+		buf.WriteString("\t\t\tt.Errorf(\"example %q: %v\", args, err)\n")
+		buf.WriteString("\t\t\tcontinue\n")
+		buf.WriteString("\t\t}\n")
+		buf.WriteString(fmt.Sprintf("\t\tif cmd.CommandPath() != %q {\n", path))
+		buf.WriteString(fmt.Sprintf("\t\t\tt.Errorf(\"example %%q: found command %%q instead of %%q\", args, cmd.CommandPath(), %q)\n", path))
+		buf.WriteString("\t\t}\n")
+		buf.WriteString("\t}\n")
+		buf.WriteString("}\n")
+	}
+
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		genExampleTests(c, buf)
+	}
+}
+
+// exampleTestName returns the name of the test function of the examples of the
+// command with the given path, e.g. "TestExamplesAppSetContext" for "app set-context".
+func exampleTestName(path string) string {
+	name := "TestExamples"
+	upper := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			name += strings.ToUpper(string(r))
+			upper = false
+		} else {
+			name += string(r)
+		}
+	}
+	return name
+}
@@ -0,0 +1,54 @@
+package doc
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenExampleTests(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	setContext := &cobra.Command{
+		Use:     "set-context <name>",
+		Args:    cobra.ExactArgs(1),
+		Example: "  $ app set-context --namespace dev staging\n  app set-context prod | tee out.txt",
+		Run:     emptyRun,
+	}
+	setContext.Flags().String("namespace", "", "")
+	version := &cobra.Command{Use: "version", Run: emptyRun}
+	root.AddCommand(setContext, version)
+
+	buf := new(bytes.Buffer)
+	if err := GenExampleTests(root, buf, "app"); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "package app\n")
+	checkStringContains(t, output, "func TestExamplesAppSetContext(t *testing.T) {\n")
+	checkStringContains(t, output, `[]string{"set-context", "--namespace", "dev", "staging"},`)
+	checkStringContains(t, output, `[]string{"set-context", "prod"},`)
+	checkStringOmits(t, output, "TestExamplesAppVersion")
+	checkStringContains(t, output, "cmd, err := rootCmd.CheckInvocation(args)\n")
+
+	// The generated tests must compile against a package declaring rootCmd.
+	fset := token.NewFileSet()
+	generated, err := parser.ParseFile(fset, "examples_test.go", output, 0)
+	if err != nil {
+		t.Fatalf("Generated source does not parse: %v", err)
+	}
+	stub, err := parser.ParseFile(fset, "root.go", "package app\n\nimport \"github.com/spf13/cobra\"\n\nvar rootCmd *cobra.Command\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("app", fset, []*ast.File{stub, generated}, nil); err != nil {
+		t.Errorf("Generated source does not compile: %v\n%s", err, output)
+	}
+}
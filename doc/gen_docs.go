@@ -2,6 +2,7 @@ package doc
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"regexp"
@@ -11,13 +12,113 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// DiagramAnnotation is the command annotation holding the path of an image, e.g.
+// an architecture diagram, to be rendered in the documentation of the command.
+const DiagramAnnotation = "diagram"
+
+// TierAnnotation is the command annotation holding the tier in which the command is
+// available, e.g. "enterprise", to be shown in the documentation of the command.
+const TierAnnotation = "tier"
+
+// StabilityAnnotation is the command annotation holding the stability level of the
+// command, "stable", "beta" or "alpha", shown as a badge in its documentation. The
+// other values are ignored.
+const StabilityAnnotation = "stability"
+
+// stabilityLevels are the known stability levels, with their description, in the order
+// of the legend.
+var stabilityLevels = []struct {
+	Name        string
+	Description string
+}{
+	{"stable", "The command is supported and only changes in backward-compatible ways."},
+	{"beta", "The command is complete but may still change in incompatible ways."},
+	{"alpha", "The command is experimental and may change or be removed without notice."},
+}
+
+// stability returns the stability level in the StabilityAnnotation of cmd, or an empty
+// string if it is absent or unknown.
+func stability(cmd *cobra.Command) string {
+	level := cmd.Annotations[StabilityAnnotation]
+	for _, known := range stabilityLevels {
+		if level == known.Name {
+			return level
+		}
+	}
+	return ""
+}
+
+// RBACAnnotation is the command annotation listing the permissions required to run the
+// command, separated by commas or line breaks, e.g. "pods:read, pods:write", shown in the
+// "Required permissions" section of its documentation.
+const RBACAnnotation = "rbac"
+
+// StdinAnnotation and StdoutAnnotation are the command annotations describing what
+// the command reads from its standard input and writes to its standard output, e.g.
+// "JSON lines, one record per line", shown in the Input and Output sections of its
+// documentation.
+const (
+	StdinAnnotation  = "stdin"
+	StdoutAnnotation = "stdout"
+)
+
+// ExternalLinksAnnotation is the command annotation holding links to resources outside
+// of the command tree related to the command, such as RFCs or API docs, as a JSON array
+// of titles and URLs, e.g. `[{"title": "RFC 7231", "url": "https://www.rfc-editor.org/rfc/rfc7231"}]`,
+// shown in the SEE ALSO section of its documentation after the links to the commands.
+const ExternalLinksAnnotation = "external_links"
+
+// ExamplesByShellAnnotation is the command annotation holding the examples of the
+// command which differ between shells, e.g. in their quoting or path separators, as a
+// JSON object mapping the name of each shell to its examples, e.g.
+// `{"bash": "app get ./config", "powershell": "app get .\\config"}`. They are shown
+// labeled by shell, in the order of the names of the shells, instead of the Example of
+// the command.
+const ExamplesByShellAnnotation = "examples_by_shell"
+
+// ShellExample holds the examples of a command for a shell.
+type ShellExample struct {
+	Shell   string // name of the shell, e.g. "bash"
+	Example string // examples of how to use the command in that shell
+}
+
+// shellExamples returns the examples in the ExamplesByShellAnnotation of cmd, sorted by
+// shell, or nil if it has none. It returns an error if the annotation is not a JSON
+// object mapping shells to examples.
+func shellExamples(cmd *cobra.Command) ([]ShellExample, error) {
+	value, ok := cmd.Annotations[ExamplesByShellAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var byShell map[string]string
+	if err := json.Unmarshal([]byte(value), &byShell); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation of %q: %v", ExamplesByShellAnnotation, cmd.CommandPath(), err)
+	}
+	var examples []ShellExample
+	for shell, example := range byShell {
+		if len(strings.TrimSpace(example)) > 0 {
+			examples = append(examples, ShellExample{Shell: shell, Example: strings.TrimRight(example, " \n")})
+		}
+	}
+	sort.Slice(examples, func(i, j int) bool { return examples[i].Shell < examples[j].Shell })
+	return examples, nil
+}
+
+// ExternalLink is a link to a resource outside of the command tree.
+type ExternalLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
 type CmdOutline struct {
 	Name          string   // full path to the command
 	Short         string   // short description of the command
 	Long          string   // long description of the command
 	UseLine       string   // full usage for a given command (including parents)
+	ArgsRule      string   // sentence describing the arguments accepted by the Args validator of the command, if any
 	Example       string   // examples of how to use the command
 	Flags         string   // default values of all non-inherited flags as a string
 	FlagSlice     []string // Flags represented as a slice
@@ -28,29 +129,212 @@ type CmdOutline struct {
 	CommandLink   string   // rendered internal link to the command
 	HeaderScale   int      // integer scale indicating depth of the current command
 	AutoGenTag    string   // automatically generated tag by Cobra
+	Diagram       string   // path of the image in the DiagramAnnotation of the command, if any
+	Tier          string   // tier in the TierAnnotation of the command, if any
+	Stability     string   // stability level in the StabilityAnnotation of the command, if known
+	StdinDesc     string   // description of the standard input in the StdinAnnotation of the command, if any
+	StdoutDesc    string   // description of the standard output in the StdoutAnnotation of the command, if any
+
+	HasDynamicArgs    bool   // whether the non-flag arguments of the command are completed dynamically
+	DynamicArgsSource string // full path of the ancestor providing that completion, if inherited
+
+	FlagOutlines        []FlagOutline  // available non-inherited flags as structured data
+	ParentFlagOutlines  []FlagOutline  // available inherited flags as structured data
+	ChildrenRefs        []CommandRef   // child commands the ChildrenLinks point to, as structured data
+	RelatedRefs         []CommandRef   // related commands the RelatedLinks point to, as structured data
+	ExclusiveGroups     [][]string     // full paths of the groups of child commands of which only one can be used
+	RequiredPermissions []string       // permissions in the RBACAnnotation of the command, if any
+	ExternalLinks       []ExternalLink // links in the ExternalLinksAnnotation of the command, if any
+
+	FlagGroups    []FlagGroupOutline // available non-inherited flags by group, if the command has flag groups
+	ShellExamples []ShellExample     // examples by shell in the ExamplesByShellAnnotation of the command, if any
+}
+
+// CommandRef describes a command referenced from the documentation of another one.
+type CommandRef struct {
+	Path    string   // full path to the command
+	Link    string   // rendered internal link to the command
+	Short   string   // short description of the command
+	Aliases []string // aliases of the command
+	Tier    string   // tier in the TierAnnotation of the command, if any
+
+	DeprecatedAliases []string // aliases of the command which are deprecated
+}
+
+func newCommandRef(cmd *cobra.Command, linkHandler func(string) string, defaultLinkGenerator func(string) string) CommandRef {
+	path := cmd.CommandPath()
+	ref := CommandRef{
+		Path:    path,
+		Link:    linkHandler(defaultLinkGenerator(path)),
+		Short:   cmd.Short,
+		Aliases: cmd.Aliases,
+		Tier:    cmd.Annotations[TierAnnotation],
+	}
+	for _, alias := range cmd.Aliases {
+		if _, deprecated := cmd.AliasDeprecation(alias); deprecated {
+			ref.DeprecatedAliases = append(ref.DeprecatedAliases, alias)
+		}
+	}
+	return ref
+}
+
+// listItem renders the reference as an item of the SEE ALSO list, followed by the
+// tier of the command, if any, and optionally by its aliases, e.g.
+// "(aliases: rm, del (deprecated))".
+func (r CommandRef) listItem(withAliases bool) string {
+	item := fmt.Sprintf("* [%s](%s)\t - %s", r.Path, r.Link, r.Short)
+	if len(r.Tier) > 0 {
+		item += " `" + r.Tier + "`"
+	}
+	if withAliases && len(r.Aliases) > 0 {
+		deprecated := make(map[string]bool, len(r.DeprecatedAliases))
+		for _, alias := range r.DeprecatedAliases {
+			deprecated[alias] = true
+		}
+		aliases := make([]string, 0, len(r.Aliases))
+		for _, alias := range r.Aliases {
+			if deprecated[alias] {
+				alias += " (deprecated)"
+			}
+			aliases = append(aliases, alias)
+		}
+		item += " (aliases: " + strings.Join(aliases, ", ") + ")"
+	}
+	return item + "\n"
+}
+
+// FlagOutline describes a single flag for use by the doc generators and custom templates.
+type FlagOutline struct {
+	Name        string   // long name of the flag
+	Shorthand   string   // one-letter abbreviated flag, if any
+	Type        string   // type name of the flag value as returned by pflag.Value.Type()
+	DefValue    string   // default value as text
+	NoOptDefVal string   // value used when the flag is present without a value
+	Usage       string   // help message
+	Implies     []string // "name=value" flag settings implied by setting the flag
+	DefinedBy   string   // full path of the ancestor defining the flag, for the inherited flags
+
+	DynamicCompletion bool // whether a completion function is registered for the flag
+}
+
+// FlagGroupOutline describes the flags of a group added with AddFlagGroup.
+type FlagGroupOutline struct {
+	Name         string        // name of the group, empty for the flags which are not part of any group
+	Flags        string        // default values of the flags of the group as a string
+	FlagOutlines []FlagOutline // flags of the group as structured data
+}
+
+// generateFlagGroupOutlines returns the outlines of the groups of the local flags of
+// cmd, beginning with the flags which are not part of any group, or nil if cmd has no
+// flag groups. The default values of the flags are rendered with defaultValue, if set.
+func generateFlagGroupOutlines(cmd *cobra.Command, completions map[string]bool, defaultValue func(flag *pflag.Flag) string) []FlagGroupOutline {
+	groups := cmd.FlagGroups()
+	if len(groups) == 0 {
+		return nil
+	}
+	var outlines []FlagGroupOutline
+	for _, name := range append([]string{""}, groups...) {
+		flags := flagsWithDefaults(cmd.LocalFlagsInGroup(name), defaultValue)
+		if !flags.HasAvailableFlags() {
+			continue
+		}
+		outlines = append(outlines, FlagGroupOutline{
+			Name:         name,
+			Flags:        cobra.FlagUsages(flags),
+			FlagOutlines: generateFlagOutlines(flags, completions),
+		})
+	}
+	return outlines
+}
+
+func generateFlagOutlines(flags *pflag.FlagSet, completions map[string]bool) []FlagOutline {
+	var outlines []FlagOutline
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if len(flag.Deprecated) > 0 || flag.Hidden {
+			return
+		}
+		outline := FlagOutline{
+			Name:        flag.Name,
+			Type:        flag.Value.Type(),
+			DefValue:    flag.DefValue,
+			NoOptDefVal: flag.NoOptDefVal,
+			Usage:       flag.Usage,
+			Implies:     cobra.FlagDependencies(flag),
+
+			DynamicCompletion: completions[flag.Name],
+		}
+		if len(flag.ShorthandDeprecated) == 0 {
+			outline.Shorthand = flag.Shorthand
+		}
+		outlines = append(outlines, outline)
+	})
+	return outlines
+}
+
+// flagOrigins returns the full paths of the ancestors defining the flags inherited by
+// cmd, by flag name.
+func flagOrigins(cmd *cobra.Command) map[string]string {
+	origins := map[string]string{}
+	for _, info := range cmd.InheritedFlagInfos() {
+		if info.DefinedBy != nil {
+			origins[info.Flag.Name] = info.DefinedBy.CommandPath()
+		}
+	}
+	return origins
+}
+
+// parentFlagUsages returns the usage of flags, inherited by cmd, as cobra.FlagUsages,
+// with the path of the ancestor defining each flag appended to its help message,
+// e.g. "config file (from app)".
+func parentFlagUsages(cmd *cobra.Command, flags *pflag.FlagSet) string {
+	origins := flagOrigins(cmd)
+	annotated := pflag.NewFlagSet("", pflag.ContinueOnError)
+	annotated.SortFlags = flags.SortFlags
+	flags.VisitAll(func(flag *pflag.Flag) {
+		flagCopy := *flag
+		if origin, ok := origins[flag.Name]; ok {
+			flagCopy.Usage = strings.TrimSpace(flag.Usage + " (from " + origin + ")")
+		}
+		annotated.AddFlag(&flagCopy)
+	})
+	return cobra.FlagUsages(annotated)
+}
+
+// hasZeroDefault returns true if the default value of the flag is the zero value of its type,
+// in which case it is not worth displaying.
+func (f FlagOutline) hasZeroDefault() bool {
+	switch f.DefValue {
+	case "", "0", "0s", "false", "[]", "<nil>":
+		return true
+	}
+	return false
 }
 
-func generateCmdOutline(cmd *cobra.Command, linkHandler func(string) string, defaultLinkGenerator func(string) string) *CmdOutline {
+func generateCmdOutline(cmd *cobra.Command, linkHandler func(string) string, defaultLinkGenerator func(string) string) (*CmdOutline, error) {
 	name := cmd.CommandPath()
 	short := cmd.Short
-	long := cmd.Long
+	long := cmd.LongText()
 	if len(long) == 0 {
 		long = short
 	}
 
 	useLine := cmd.UseLine()
 
-	example := cmd.Example
+	example := cmd.ExampleText()
 
-	buf := new(bytes.Buffer)
+	examples, err := shellExamples(cmd)
+	if err != nil {
+		return nil, err
+	}
+	links, err := externalLinks(cmd)
+	if err != nil {
+		return nil, err
+	}
 
 	var flagString string
 	flags := cmd.NonInheritedFlags()
-	flags.SetOutput(buf)
 	if flags.HasAvailableFlags() {
-		flags.PrintDefaults()
-		flagString = buf.String()
-		buf.Reset()
+		flagString = cobra.FlagUsages(flags)
 	}
 
 	var flagSlice []string
@@ -63,13 +347,19 @@ func generateCmdOutline(cmd *cobra.Command, linkHandler func(string) string, def
 		}
 	}
 
+	completions := cmd.FlagCompletionFuncs()
+	flagOutlines := generateFlagOutlines(flags, completions)
+	flagGroups := generateFlagGroupOutlines(cmd, completions, nil)
+
 	var parentFlagString string
 	parentFlags := cmd.InheritedFlags()
-	parentFlags.SetOutput(buf)
 	if parentFlags.HasAvailableFlags() {
-		parentFlags.PrintDefaults()
-		parentFlagString = buf.String()
-		buf.Reset()
+		parentFlagString = cobra.FlagUsages(parentFlags)
+	}
+	parentFlagOutlines := generateFlagOutlines(parentFlags, completions)
+	origins := flagOrigins(cmd)
+	for i := range parentFlagOutlines {
+		parentFlagOutlines[i].DefinedBy = origins[parentFlagOutlines[i].Name]
 	}
 
 	headerScale := 0
@@ -89,32 +379,44 @@ func generateCmdOutline(cmd *cobra.Command, linkHandler func(string) string, def
 	}
 
 	var childrenLinks []string
-	children := cmd.Commands()
-	sort.Sort(byName(children))
+	var childrenRefs []CommandRef
+	children := sortedCommands(cmd)
 
 	for _, child := range children {
-		var childLink string
 		if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
 			continue
 		}
-		cname := name + " " + child.Name()
-		link := defaultLinkGenerator(cname)
-		childLink = fmt.Sprintf("* [%s](%s)\t - %s\n", cname, linkHandler(link), child.Short)
-		childrenLinks = append(childrenLinks, childLink)
+		childRef := newCommandRef(child, linkHandler, defaultLinkGenerator)
+		childrenRefs = append(childrenRefs, childRef)
+		childrenLinks = append(childrenLinks, childRef.listItem(false))
+	}
+
+	var exclusiveGroups [][]string
+	for _, group := range cmd.ExclusiveCommandGroups() {
+		paths := make([]string, 0, len(group))
+		for _, child := range group {
+			paths = append(paths, child.CommandPath())
+		}
+		exclusiveGroups = append(exclusiveGroups, paths)
 	}
 
 	var relatedLinks []string
+	var relatedRefs []CommandRef
 	relatedCmds := cmd.RelatedCommands()
 
 	for _, relCmd := range relatedCmds {
-		var relatedLink string
 		if !relCmd.IsAvailableCommand() || relCmd.IsAdditionalHelpTopicCommand() {
 			continue
 		}
-		rname := relCmd.CommandPath()
-		link := defaultLinkGenerator(rname)
-		relatedLink = fmt.Sprintf("* [%s](%s)\t - %s\n", rname, linkHandler(link), relCmd.Short)
-		relatedLinks = append(relatedLinks, relatedLink)
+		relatedRef := newCommandRef(relCmd, linkHandler, defaultLinkGenerator)
+		relatedRefs = append(relatedRefs, relatedRef)
+		relatedLinks = append(relatedLinks, relatedRef.listItem(false))
+	}
+
+	var dynamicArgsSource string
+	argsSource := cmd.ArgsCompletionSource()
+	if argsSource != nil && argsSource != cmd {
+		dynamicArgsSource = argsSource.CommandPath()
 	}
 
 	var commandLink string
@@ -138,7 +440,63 @@ func generateCmdOutline(cmd *cobra.Command, linkHandler func(string) string, def
 		CommandLink:   commandLink,
 		HeaderScale:   headerScale,
 		AutoGenTag:    autoGenTag,
+		Diagram:       cmd.Annotations[DiagramAnnotation],
+		Tier:          cmd.Annotations[TierAnnotation],
+		Stability:     stability(cmd),
+		ArgsRule:      cobra.DescribeArgs(cmd.Args),
+		StdinDesc:     strings.TrimSpace(cmd.Annotations[StdinAnnotation]),
+		StdoutDesc:    strings.TrimSpace(cmd.Annotations[StdoutAnnotation]),
+
+		HasDynamicArgs:    argsSource != nil,
+		DynamicArgsSource: dynamicArgsSource,
+
+		FlagOutlines:        flagOutlines,
+		FlagGroups:          flagGroups,
+		ShellExamples:       examples,
+		ParentFlagOutlines:  parentFlagOutlines,
+		ChildrenRefs:        childrenRefs,
+		RelatedRefs:         relatedRefs,
+		ExclusiveGroups:     exclusiveGroups,
+		RequiredPermissions: requiredPermissions(cmd),
+		ExternalLinks:       links,
+	}, nil
+}
+
+// requiredPermissions returns the permissions listed in the RBACAnnotation of cmd.
+func requiredPermissions(cmd *cobra.Command) []string {
+	var permissions []string
+	for _, permission := range strings.FieldsFunc(cmd.Annotations[RBACAnnotation], func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		if permission = strings.TrimSpace(permission); len(permission) > 0 {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
+
+// externalLinks returns the links in the ExternalLinksAnnotation of cmd, ignoring the
+// links without URL. It returns an error if the annotation is not a JSON array of links.
+func externalLinks(cmd *cobra.Command) ([]ExternalLink, error) {
+	value, ok := cmd.Annotations[ExternalLinksAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var links []ExternalLink
+	if err := json.Unmarshal([]byte(value), &links); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation of %q: %v", ExternalLinksAnnotation, cmd.CommandPath(), err)
+	}
+	valid := links[:0]
+	for _, link := range links {
+		if len(link.URL) == 0 {
+			continue
+		}
+		if len(link.Title) == 0 {
+			link.Title = link.URL
+		}
+		valid = append(valid, link)
 	}
+	return valid, nil
 }
 
 // GenDocsCustomTemplate takes in a command, an output stream, a linkHandler to customize automatically rendered internal links,
@@ -149,9 +507,12 @@ func GenDocsCustomTemplate(cmd *cobra.Command, w io.Writer, linkHandler func(str
 
 	buf := new(bytes.Buffer)
 
-	cmdOutline := generateCmdOutline(cmd, linkHandler, templateDefaultLinkGenerator)
+	cmdOutline, err := generateCmdOutline(cmd, linkHandler, templateDefaultLinkGenerator)
+	if err != nil {
+		return err
+	}
 
-	err := writeToTemplate(cmdOutline, template, buf)
+	err = writeToTemplate(cmdOutline, template, buf)
 	if err != nil {
 		return err
 	}
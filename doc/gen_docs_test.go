@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 	"text/template"
+
+	"github.com/spf13/cobra"
 )
 
 func TestGenDocsCustomTemplate(t *testing.T) {
@@ -93,3 +95,34 @@ Just run cobra-test echo
 		t.Error("Generated output did not match expected output")
 	}
 }
+
+func TestCmdOutlineRequiredFlagsInUseLine(t *testing.T) {
+	c := &cobra.Command{Use: "create <name>", RequiredFlagsInUseLine: true, Run: emptyRun}
+	c.Flags().String("type", "", "type of the resource")
+	c.Flags().String("description", "", "description of the resource")
+	_ = c.MarkFlagRequired("type")
+
+	cmdOutline, err := generateCmdOutline(c, func(s string) string { return s }, mdDefaultLinkHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "create <name> --type <value> [flags]"
+	if cmdOutline.UseLine != expected {
+		t.Errorf("expected: %q, got: %q", expected, cmdOutline.UseLine)
+	}
+}
+
+func TestGenDocsCustomTemplateCommandRefs(t *testing.T) {
+	tpl := template.Must(template.New("refs").Parse(
+		`{{range .ChildrenRefs}}{{.Path}} -> {{.Link}}{{range .Aliases}} ({{.}}){{end}}
+{{end}}`))
+
+	out := new(bytes.Buffer)
+	if err := GenDocsCustomTemplate(rootCmd, out, func(s string) string { return "#" + s }, tpl); err != nil {
+		t.Fatal(err)
+	}
+	expected := "root echo -> #root-echo (say)\nroot print -> #root-print\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, out.String())
+	}
+}
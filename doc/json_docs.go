@@ -0,0 +1,127 @@
+package doc
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type jsonFlag struct {
+	Name         string `json:"name"`
+	Shorthand    string `json:"shorthand,omitempty"`
+	Type         string `json:"type"`
+	DefaultValue string `json:"defaultValue,omitempty"`
+	Usage        string `json:"usage,omitempty"`
+	DefinedBy    string `json:"definedBy,omitempty"`
+}
+
+type jsonCmdDoc struct {
+	Name        string `json:"name"`
+	Synopsis    string `json:"synopsis,omitempty"`
+	Description string `json:"description,omitempty"`
+	Usage       string `json:"usage,omitempty"`
+	Example     string `json:"example,omitempty"`
+	// Examples are the examples of Example, as parsed by StructuredExamples.
+	Examples []cobra.Example `json:"examples,omitempty"`
+	// GlobalFlags are the persistent flags of the root command, InheritedFlags those of
+	// the other ancestors, and LocalFlags those defined by the command itself.
+	GlobalFlags    []jsonFlag `json:"globalFlags,omitempty"`
+	InheritedFlags []jsonFlag `json:"inheritedFlags,omitempty"`
+	LocalFlags     []jsonFlag `json:"localFlags,omitempty"`
+	Commands       []string   `json:"commands,omitempty"`
+}
+
+// GenJSONTree creates JSON structured ref files for this command and all descendants
+// in the directory given, named like the files of GenYamlTree.
+func GenJSONTree(cmd *cobra.Command, dir string) error {
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		if err := GenJSONTree(c, dir); err != nil {
+			return err
+		}
+	}
+
+	basename := strings.Replace(cmd.CommandPath(), " ", "_", -1) + ".json"
+	f, err := os.Create(filepath.Join(dir, basename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return GenJSON(cmd, f)
+}
+
+// GenJSON creates JSON output, for frontends rendering the reference of the command.
+// The global flags, persistent flags of the root command, the flags inherited from
+// the other ancestors and the local flags of the command are listed separately.
+func GenJSON(cmd *cobra.Command, w io.Writer) error {
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+
+	jsonDoc := jsonCmdDoc{
+		Name:        cmd.CommandPath(),
+		Synopsis:    cmd.Short,
+		Description: cmd.LongText(),
+		Example:     cmd.ExampleText(),
+		Examples:    cmd.StructuredExamples(),
+	}
+	if cmd.Runnable() {
+		jsonDoc.Usage = cmd.UseLine()
+	}
+
+	jsonDoc.LocalFlags = genJSONFlags(cmd.NonInheritedFlags())
+	for _, info := range cmd.InheritedFlagInfos() {
+		if info.Flag.Hidden {
+			continue
+		}
+		flag := newJSONFlag(info.Flag)
+		if info.DefinedBy == nil || info.DefinedBy == cmd.Root() {
+			jsonDoc.GlobalFlags = append(jsonDoc.GlobalFlags, flag)
+		} else {
+			flag.DefinedBy = info.DefinedBy.CommandPath()
+			jsonDoc.InheritedFlags = append(jsonDoc.InheritedFlags, flag)
+		}
+	}
+
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		jsonDoc.Commands = append(jsonDoc.Commands, c.CommandPath())
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&jsonDoc)
+}
+
+func genJSONFlags(flags *pflag.FlagSet) []jsonFlag {
+	var result []jsonFlag
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		result = append(result, newJSONFlag(flag))
+	})
+	return result
+}
+
+func newJSONFlag(flag *pflag.Flag) jsonFlag {
+	result := jsonFlag{
+		Name:         flag.Name,
+		Type:         flag.Value.Type(),
+		DefaultValue: flag.DefValue,
+		Usage:        flag.Usage,
+	}
+	if len(flag.ShorthandDeprecated) == 0 {
+		result.Shorthand = flag.Shorthand
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenJSONFlagSources(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{Use: "get", Run: emptyRun}
+	pods := &cobra.Command{Use: "pods", Short: "List the pods", Run: emptyRun}
+	root.AddCommand(get)
+	get.AddCommand(pods)
+	root.PersistentFlags().String("config", "", "config file")
+	get.PersistentFlags().StringP("output", "o", "text", "output format")
+	pods.Flags().Bool("all", false, "list the pods of all the namespaces")
+
+	buf := new(bytes.Buffer)
+	if err := GenJSON(pods, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc jsonCmdDoc
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, buf.String())
+	}
+	if doc.Name != "app get pods" {
+		t.Errorf("Expected name %q, got %q", "app get pods", doc.Name)
+	}
+	if len(doc.GlobalFlags) != 1 || doc.GlobalFlags[0].Name != "config" {
+		t.Errorf("Expected the global flags to be [config], got %v", doc.GlobalFlags)
+	}
+	if len(doc.InheritedFlags) != 1 || doc.InheritedFlags[0].Name != "output" || doc.InheritedFlags[0].DefinedBy != "app get" {
+		t.Errorf("Expected the inherited flags to be [output of app get], got %v", doc.InheritedFlags)
+	}
+	localFlags := map[string]bool{}
+	for _, flag := range doc.LocalFlags {
+		localFlags[flag.Name] = true
+	}
+	if !localFlags["all"] || localFlags["config"] || localFlags["output"] {
+		t.Errorf("Expected the local flags to include all but not config nor output, got %v", doc.LocalFlags)
+	}
+}
+
+func TestGenJSONExamples(t *testing.T) {
+	cmd := &cobra.Command{
+		Use:     "app",
+		Example: "# Print the version\napp version",
+		Run:     emptyRun,
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenJSON(cmd, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc jsonCmdDoc
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, buf.String())
+	}
+	expected := cobra.Example{Description: "Print the version", Command: "app version"}
+	if len(doc.Examples) != 1 || doc.Examples[0] != expected {
+		t.Errorf("Expected the examples to be [%v], got %v", expected, doc.Examples)
+	}
+}
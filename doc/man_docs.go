@@ -19,7 +19,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +28,17 @@ import (
 	"github.com/spf13/pflag"
 )
 
+// EnvAnnotation is the command annotation listing the environment variables the
+// command honors, rendered in the ENVIRONMENT section of its man page. It holds one
+// variable per line, followed by its description after a tab, e.g.
+// "APP_TOKEN\tThe token used to authenticate".
+const EnvAnnotation = "env"
+
+// FilesAnnotation is the command annotation listing the files the command reads,
+// rendered in the FILES section of its man page. It holds one path per line,
+// followed by its description after a tab, e.g. "~/.app.yaml\tThe configuration".
+const FilesAnnotation = "files"
+
 // GenManTree will generate a man page for this command and all descendants
 // in the directory given. The header may be nil. This function may not work
 // correctly if your command names have `-` in them. If you have `cmd` with two
@@ -109,8 +119,31 @@ func GenMan(cmd *cobra.Command, header *GenManHeader, w io.Writer) error {
 		return err
 	}
 
-	b := genMan(cmd, header)
-	_, err := w.Write(md2man.Render(b))
+	b, err := genMan(cmd, header)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(md2man.Render(b))
+	return err
+}
+
+// GenManSingle will generate a single man page for the given command and all
+// its descendants, and write it to w. Each available subcommand is rendered as a
+// subsection of a SUBCOMMANDS section, with its synopsis, options and examples.
+// The header argument may be nil.
+func GenManSingle(cmd *cobra.Command, header *GenManHeader, w io.Writer) error {
+	if header == nil {
+		header = &GenManHeader{}
+	}
+	if err := fillHeader(header, cmd.CommandPath()); err != nil {
+		return err
+	}
+
+	b, err := genManSingle(cmd, header)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(md2man.Render(b))
 	return err
 }
 
@@ -140,7 +173,7 @@ func fillHeader(header *GenManHeader, name string) error {
 }
 
 func manPreamble(buf *bytes.Buffer, header *GenManHeader, cmd *cobra.Command, dashedName string) {
-	description := cmd.Long
+	description := cmd.LongText()
 	if len(description) == 0 {
 		description = cmd.Short
 	}
@@ -186,13 +219,26 @@ func manPrintFlags(buf *bytes.Buffer, flags *pflag.FlagSet) {
 }
 
 func manPrintOptions(buf *bytes.Buffer, command *cobra.Command) {
-	flags := command.NonInheritedFlags()
-	if flags.HasAvailableFlags() {
+	if len(command.FlagGroups()) > 0 {
+		for _, group := range append([]string{""}, command.FlagGroups()...) {
+			flags := command.LocalFlagsInGroup(group)
+			if !flags.HasAvailableFlags() {
+				continue
+			}
+			title := "OPTIONS"
+			if len(group) > 0 {
+				title = strings.ToUpper(group) + " OPTIONS"
+			}
+			buf.WriteString("# " + title + "\n")
+			manPrintFlags(buf, flags)
+			buf.WriteString("\n")
+		}
+	} else if flags := command.NonInheritedFlags(); flags.HasAvailableFlags() {
 		buf.WriteString("# OPTIONS\n")
 		manPrintFlags(buf, flags)
 		buf.WriteString("\n")
 	}
-	flags = command.InheritedFlags()
+	flags := command.InheritedFlags()
 	if flags.HasAvailableFlags() {
 		buf.WriteString("# OPTIONS INHERITED FROM PARENT COMMANDS\n")
 		manPrintFlags(buf, flags)
@@ -200,7 +246,69 @@ func manPrintOptions(buf *bytes.Buffer, command *cobra.Command) {
 	}
 }
 
-func genMan(cmd *cobra.Command, header *GenManHeader) []byte {
+// manPrintExample renders the examples of cmd as a section, labeled by shell if it has
+// examples by shell.
+func manPrintExample(buf *bytes.Buffer, cmd *cobra.Command) error {
+	examples, err := shellExamples(cmd)
+	if err != nil {
+		return err
+	}
+	if len(examples) > 0 {
+		buf.WriteString("# EXAMPLE\n")
+		for _, example := range examples {
+			buf.WriteString(fmt.Sprintf("**%s**\n\n```\n%s\n```\n\n", example.Shell, example.Example))
+		}
+		return nil
+	}
+	if example := cmd.ExampleText(); len(example) > 0 {
+		buf.WriteString("# EXAMPLE\n")
+		buf.WriteString(fmt.Sprintf("```\n%s\n```\n", example))
+	}
+	return nil
+}
+
+// manPrintPermissions renders the permissions required by cmd as a section.
+func manPrintPermissions(buf *bytes.Buffer, cmd *cobra.Command) {
+	permissions := requiredPermissions(cmd)
+	if len(permissions) == 0 {
+		return
+	}
+	buf.WriteString("# PERMISSIONS\n")
+	for _, permission := range permissions {
+		buf.WriteString("**" + permission + "**\n\n")
+	}
+	buf.WriteString("\n")
+}
+
+// manPrintTextSection renders the text of the annotation of cmd as a section.
+func manPrintTextSection(buf *bytes.Buffer, cmd *cobra.Command, annotation, title string) {
+	value := strings.TrimSpace(cmd.Annotations[annotation])
+	if len(value) == 0 {
+		return
+	}
+	buf.WriteString("# " + title + "\n" + value + "\n\n")
+}
+
+// manPrintAnnotationSection renders the entries of the annotation of cmd, made of
+// a name and a description separated by a tab on each line, as a section.
+func manPrintAnnotationSection(buf *bytes.Buffer, cmd *cobra.Command, annotation, title string) {
+	value := strings.TrimSpace(cmd.Annotations[annotation])
+	if len(value) == 0 {
+		return
+	}
+	buf.WriteString("# " + title + "\n")
+	for _, line := range strings.Split(value, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+		buf.WriteString("**" + parts[0] + "**\n")
+		if len(parts) > 1 {
+			buf.WriteString("\t" + strings.TrimSpace(parts[1]) + "\n")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+}
+
+func genMan(cmd *cobra.Command, header *GenManHeader) ([]byte, error) {
 	cmd.InitDefaultHelpCmd()
 	cmd.InitDefaultHelpFlag()
 
@@ -211,11 +319,19 @@ func genMan(cmd *cobra.Command, header *GenManHeader) []byte {
 
 	manPreamble(buf, header, cmd, dashCommandName)
 	manPrintOptions(buf, cmd)
-	if len(cmd.Example) > 0 {
-		buf.WriteString("# EXAMPLE\n")
-		buf.WriteString(fmt.Sprintf("```\n%s\n```\n", cmd.Example))
+	manPrintTextSection(buf, cmd, StdinAnnotation, "INPUT")
+	manPrintTextSection(buf, cmd, StdoutAnnotation, "OUTPUT")
+	manPrintPermissions(buf, cmd)
+	manPrintAnnotationSection(buf, cmd, EnvAnnotation, "ENVIRONMENT")
+	manPrintAnnotationSection(buf, cmd, FilesAnnotation, "FILES")
+	if err := manPrintExample(buf, cmd); err != nil {
+		return nil, err
+	}
+	links, err := externalLinks(cmd)
+	if err != nil {
+		return nil, err
 	}
-	if hasSeeAlso(cmd) {
+	if hasSeeAlso(cmd) || len(links) > 0 {
 		buf.WriteString("# SEE ALSO\n")
 		seealsos := make([]string, 0)
 		if cmd.HasParent() {
@@ -229,8 +345,7 @@ func genMan(cmd *cobra.Command, header *GenManHeader) []byte {
 				}
 			})
 		}
-		children := cmd.Commands()
-		sort.Sort(byName(children))
+		children := sortedCommands(cmd)
 		for _, c := range children {
 			if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
 				continue
@@ -238,10 +353,73 @@ func genMan(cmd *cobra.Command, header *GenManHeader) []byte {
 			seealso := fmt.Sprintf("**%s-%s(%s)**", dashCommandName, c.Name(), header.Section)
 			seealsos = append(seealsos, seealso)
 		}
+		for _, link := range links {
+			seealsos = append(seealsos, fmt.Sprintf("%s <%s>", link.Title, link.URL))
+		}
 		buf.WriteString(strings.Join(seealsos, ", ") + "\n")
 	}
 	if !cmd.DisableAutoGenTag {
 		buf.WriteString(fmt.Sprintf("# HISTORY\n%s Auto generated by spf13/cobra\n", header.Date.Format("2-Jan-2006")))
 	}
-	return buf.Bytes()
+	return buf.Bytes(), nil
+}
+
+func genManSingle(cmd *cobra.Command, header *GenManHeader) ([]byte, error) {
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+
+	dashCommandName := strings.Replace(cmd.CommandPath(), " ", "-", -1)
+
+	buf := new(bytes.Buffer)
+
+	manPreamble(buf, header, cmd, dashCommandName)
+	manPrintOptions(buf, cmd)
+	manPrintTextSection(buf, cmd, StdinAnnotation, "INPUT")
+	manPrintTextSection(buf, cmd, StdoutAnnotation, "OUTPUT")
+	manPrintPermissions(buf, cmd)
+	manPrintAnnotationSection(buf, cmd, EnvAnnotation, "ENVIRONMENT")
+	manPrintAnnotationSection(buf, cmd, FilesAnnotation, "FILES")
+	if err := manPrintExample(buf, cmd); err != nil {
+		return nil, err
+	}
+
+	subBuf := new(bytes.Buffer)
+	manPrintSubcommands(subBuf, cmd)
+	if subBuf.Len() > 0 {
+		buf.WriteString("# SUBCOMMANDS\n")
+		subBuf.WriteTo(buf)
+	}
+
+	if !cmd.DisableAutoGenTag {
+		buf.WriteString(fmt.Sprintf("# HISTORY\n%s Auto generated by spf13/cobra\n", header.Date.Format("2-Jan-2006")))
+	}
+	return buf.Bytes(), nil
+}
+
+// manPrintSubcommands renders every available descendant of cmd as a subsection.
+func manPrintSubcommands(buf *bytes.Buffer, cmd *cobra.Command) {
+	children := sortedCommands(cmd)
+	for _, c := range children {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		c.InitDefaultHelpFlag()
+
+		buf.WriteString(fmt.Sprintf("### %s\n", c.CommandPath()))
+		buf.WriteString(c.Short + "\n\n")
+		buf.WriteString(fmt.Sprintf("**%s**\n\n", c.UseLine()))
+		if long := c.LongText(); len(long) > 0 {
+			buf.WriteString(long + "\n\n")
+		}
+		if flags := c.NonInheritedFlags(); flags.HasAvailableFlags() {
+			buf.WriteString("**Options**\n\n")
+			manPrintFlags(buf, flags)
+		}
+		if example := c.ExampleText(); len(example) > 0 {
+			buf.WriteString("**Example**\n\n")
+			buf.WriteString(fmt.Sprintf("```\n%s\n```\n\n", example))
+		}
+
+		manPrintSubcommands(buf, c)
+	}
 }
@@ -211,3 +211,182 @@ func BenchmarkGenManToFile(b *testing.B) {
 		}
 	}
 }
+
+func TestGenManSingle(t *testing.T) {
+	header := &GenManHeader{Title: "Project", Section: "1"}
+
+	buf := new(bytes.Buffer)
+	if err := GenManSingle(rootCmd, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, ".SH SUBCOMMANDS")
+	checkStringContains(t, output, ".SS root echo times")
+	checkStringContains(t, output, translate("--inttwo"))
+	checkStringContains(t, output, translate(echoCmd.Example))
+	checkStringOmits(t, output, translate(deprecatedCmd.Short))
+	checkStringOmits(t, output, ".SS root help")
+
+	// The options of the nested subcommand are part of its subsection
+	timesSection := output[strings.Index(output, ".SS root echo times"):]
+	checkStringContains(t, timesSection, translate("--booltwo"))
+}
+
+func TestGenManFilesAndEnvironment(t *testing.T) {
+	header := &GenManHeader{Title: "Project", Section: "1"}
+	c := &cobra.Command{
+		Use:   "app",
+		Short: "An application",
+		Run:   emptyRun,
+		Annotations: map[string]string{
+			EnvAnnotation:   "APP_TOKEN\tThe token used to authenticate\nAPP_DEBUG\tEnables the debug logs",
+			FilesAnnotation: "~/.app.yaml\tThe user configuration",
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMan(c, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, ".SH ENVIRONMENT")
+	checkStringContains(t, output, `\fBAPP\_TOKEN\fP`)
+	checkStringContains(t, output, "The token used to authenticate")
+	checkStringContains(t, output, `\fBAPP\_DEBUG\fP`)
+	checkStringContains(t, output, ".SH FILES")
+	checkStringContains(t, output, "The user configuration")
+	if strings.Index(output, ".SH ENVIRONMENT") > strings.Index(output, ".SH FILES") {
+		t.Error("Expected the ENVIRONMENT section before the FILES section")
+	}
+
+	buf.Reset()
+	if err := GenMan(echoCmd, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), ".SH ENVIRONMENT")
+	checkStringOmits(t, buf.String(), ".SH FILES")
+}
+
+func TestGenManInputAndOutput(t *testing.T) {
+	header := &GenManHeader{Title: "Project", Section: "1"}
+	c := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert records",
+		Run:   emptyRun,
+		Annotations: map[string]string{
+			StdinAnnotation:  "CSV records, with a header line",
+			StdoutAnnotation: "JSON lines, one record per line",
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMan(c, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, ".SH INPUT\n.PP\nCSV records, with a header line")
+	checkStringContains(t, output, ".SH OUTPUT\n.PP\nJSON lines, one record per line")
+
+	buf.Reset()
+	if err := GenMan(echoCmd, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), ".SH INPUT")
+	checkStringOmits(t, buf.String(), ".SH OUTPUT")
+}
+
+func TestGenManPermissions(t *testing.T) {
+	header := &GenManHeader{Title: "Project", Section: "1"}
+	c := &cobra.Command{
+		Use:         "deploy",
+		Short:       "Deploy the application",
+		Annotations: map[string]string{RBACAnnotation: "deployments:write, pods:read"},
+		Run:         emptyRun,
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMan(c, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), ".SH PERMISSIONS\n.PP\n\\fBdeployments:write\\fP\n\n.PP\n\\fBpods:read\\fP")
+
+	buf.Reset()
+	if err := GenMan(echoCmd, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), ".SH PERMISSIONS")
+}
+
+func TestGenManExternalLinks(t *testing.T) {
+	header := &GenManHeader{Title: "Project", Section: "1"}
+	c := &cobra.Command{
+		Use: "get",
+		Annotations: map[string]string{
+			ExternalLinksAnnotation: `[{"title": "RFC 7231", "url": "https://www.rfc-editor.org/rfc/rfc7231"}]`,
+		},
+		Run: emptyRun,
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMan(c, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+	checkStringContains(t, output, ".SH SEE ALSO")
+	checkStringContains(t, output, "RFC 7231")
+	checkStringContains(t, output, "https://www.rfc-editor.org/rfc/rfc7231")
+
+	c.Annotations[ExternalLinksAnnotation] = `[{"title": "RFC 7231", "url": 7231}]`
+	if err := GenMan(c, header, new(bytes.Buffer)); err == nil {
+		t.Error("expected an error for an invalid external_links annotation")
+	}
+}
+
+func TestGenManFlagGroups(t *testing.T) {
+	c := &cobra.Command{Use: "connect", Run: emptyRun}
+	c.Flags().String("host", "", "server host")
+	c.Flags().Bool("verbose", false, "verbose output")
+	if err := c.AddFlagGroup("Connection", "host"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMan(c, nil, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	options := strings.Index(output, ".SH OPTIONS")
+	connection := strings.Index(output, ".SH CONNECTION OPTIONS")
+	if options < 0 || connection < options {
+		t.Fatalf("Expected the OPTIONS section followed by the CONNECTION OPTIONS section, got:\n%s", output)
+	}
+	checkStringContains(t, output[options:connection], "verbose")
+	checkStringOmits(t, output[options:connection], "host")
+	checkStringContains(t, output[connection:], "host")
+}
+
+func TestGenManShellExamples(t *testing.T) {
+	c := &cobra.Command{
+		Use:         "get",
+		Example:     "get ./config",
+		Annotations: map[string]string{ExamplesByShellAnnotation: `{"bash": "get ./config", "powershell": "get .\\config"}`},
+		Run:         emptyRun,
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMan(c, nil, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	bash := strings.Index(output, "bash")
+	powershell := strings.Index(output, "powershell")
+	if bash < 0 || powershell < bash {
+		t.Fatalf("Expected the bash example followed by the PowerShell one, got:\n%s", output)
+	}
+	checkStringContains(t, output[powershell:], `get .\\config`)
+}
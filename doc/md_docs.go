@@ -19,18 +19,448 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
+	"unicode"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-func printOptions(buf *bytes.Buffer, cmdOutline *CmdOutline) error {
-	if len(cmdOutline.Flags) > 0 {
+func printOptions(buf *bytes.Buffer, cmdOutline *CmdOutline, opts GenMarkdownOptions, globalOptionsLink string) error {
+	listMode := opts.TypeLinkHandler != nil || opts.FlagGlossaryLink != nil
+
+	if len(cmdOutline.FlagGroups) > 0 {
+		for _, group := range cmdOutline.FlagGroups {
+			title := "Options"
+			if len(group.Name) > 0 {
+				title = group.Name + " options"
+			}
+			if listMode {
+				buf.WriteString("### " + title + "\n\n")
+				printFlagList(buf, group.FlagOutlines, opts)
+				continue
+			}
+			buf.WriteString(fmt.Sprintf("### %s\n\n```\n%s```\n\n", title, group.Flags))
+			printFlagDependencies(buf, group.FlagOutlines)
+			printFlagCompletions(buf, group.FlagOutlines)
+		}
+	} else if listMode {
+		if len(cmdOutline.FlagOutlines) > 0 {
+			buf.WriteString("### Options\n\n")
+			printFlagList(buf, cmdOutline.FlagOutlines, opts)
+		}
+	} else if len(cmdOutline.Flags) > 0 {
 		buf.WriteString(fmt.Sprintf("### Options\n\n```\n%s```\n\n", cmdOutline.Flags))
+		printFlagDependencies(buf, cmdOutline.FlagOutlines)
+		printFlagCompletions(buf, cmdOutline.FlagOutlines)
 	}
 
-	if len(cmdOutline.ParentFlags) > 0 {
-		buf.WriteString(fmt.Sprintf("### Options inherited from parent commands\n\n```\n%s```\n\n", cmdOutline.ParentFlags))
+	if len(cmdOutline.ParentFlags) > 0 || len(globalOptionsLink) > 0 {
+		title := "Options inherited from parent commands"
+		if opts.InheritedFlagsCollapsible {
+			buf.WriteString("<details>\n<summary>" + title + "</summary>\n\n")
+		} else {
+			buf.WriteString("### " + title + "\n\n")
+		}
+		if len(cmdOutline.ParentFlags) > 0 {
+			if listMode {
+				printFlagList(buf, cmdOutline.ParentFlagOutlines, opts)
+			} else {
+				buf.WriteString(fmt.Sprintf("```\n%s```\n\n", cmdOutline.ParentFlags))
+			}
+		}
+		if len(globalOptionsLink) > 0 {
+			buf.WriteString(globalOptionsLink)
+		}
+		if opts.InheritedFlagsCollapsible {
+			buf.WriteString("</details>\n\n")
+		}
+	}
+	return nil
+}
+
+// printFlagDependencies lists the flag settings implied by each flag, which
+// cannot be shown in the code block of the options.
+func printFlagDependencies(buf *bytes.Buffer, flags []FlagOutline) {
+	found := false
+	for _, flag := range flags {
+		if len(flag.Implies) == 0 {
+			continue
+		}
+		buf.WriteString("Setting `--" + flag.Name + "` implies `--" + strings.Join(flag.Implies, "`, `--") + "`.\n")
+		found = true
+	}
+	if found {
+		buf.WriteString("\n")
+	}
+}
+
+// printFlagCompletions lists the flags for which dynamic completion is available,
+// which cannot be shown in the code block of the options.
+func printFlagCompletions(buf *bytes.Buffer, flags []FlagOutline) {
+	var names []string
+	for _, flag := range flags {
+		if flag.DynamicCompletion {
+			names = append(names, "`--"+flag.Name+"`")
+		}
+	}
+	if len(names) > 0 {
+		buf.WriteString("Dynamic completion is available for " + strings.Join(names, ", ") + ".\n\n")
+	}
+}
+
+// printFlagList renders flags as a Markdown list instead of a code block,
+// so that parts of each entry can be rendered as links.
+func printFlagList(buf *bytes.Buffer, flags []FlagOutline, opts GenMarkdownOptions) {
+	for _, flag := range flags {
+		name := "--" + flag.Name
+		if len(flag.Shorthand) > 0 {
+			name = "-" + flag.Shorthand + ", " + name
+		}
+		name = "`" + name + "`"
+		if opts.FlagGlossaryLink != nil {
+			if link := opts.FlagGlossaryLink(flag.Name); len(link) > 0 {
+				name = "[" + name + "](" + link + ")"
+			}
+		}
+		buf.WriteString("* " + name)
+		// Like in the help output, boolean flags do not display their type.
+		if flag.Type != "bool" {
+			typeName := cobra.FlagTypeName(flag.Type)
+			if opts.TypeLinkHandler != nil {
+				typeName = fmt.Sprintf("[%s](%s)", typeName, opts.TypeLinkHandler(flag.Type))
+			}
+			buf.WriteString(" " + typeName)
+		}
+		if len(flag.Usage) > 0 {
+			buf.WriteString(": " + flag.Usage)
+		}
+		if opts.ShowFlagOrigins && len(flag.DefinedBy) > 0 {
+			buf.WriteString(" (from " + flag.DefinedBy + ")")
+		}
+		if !flag.hasZeroDefault() {
+			if flag.Type == "string" {
+				buf.WriteString(fmt.Sprintf(" (default %q)", flag.DefValue))
+			} else {
+				buf.WriteString(fmt.Sprintf(" (default %s)", flag.DefValue))
+			}
+		}
+		if len(flag.Implies) > 0 {
+			buf.WriteString(" (implies `--" + strings.Join(flag.Implies, "`, `--") + "`)")
+		}
+		if flag.DynamicCompletion {
+			buf.WriteString(" (dynamic completion available)")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+}
+
+// GenMarkdownOptions is the options for generating markdown pages.
+// Used in GenMarkdownFromOpts and GenMarkdownTreeFromOpts.
+type GenMarkdownOptions struct {
+	// Path is the directory the pages are written to. Only used by GenMarkdownTreeFromOpts.
+	Path string
+	// FilePrepender returns the content written at the top of the page, given its full
+	// file path. Only used by GenMarkdownTreeFromOpts.
+	FilePrepender func(string) string
+	// FrontMatter returns the content written at the top of the page of the given command,
+	// after the content returned by FilePrepender, e.g. DocusaurusFrontMatter.
+	// Only used by GenMarkdownTreeFromOpts.
+	FrontMatter func(cmd *cobra.Command) string
+	// LinkHandler customizes the rendered internal links to the commands, given a filename.
+	LinkHandler func(string) string
+	// TypeLinkHandler, if set, renders the value type of each flag as a link
+	// to the target it returns for the type name, e.g. "#type-duration".
+	// The options are then rendered as a list instead of a code block.
+	TypeLinkHandler func(typeName string) string
+	// FlagGlossaryLink, if set, renders the name of each flag as a link to the target
+	// it returns for the flag name, e.g. "glossary.md#flag-output", to link the options
+	// to a glossary describing the flags shared by the commands in depth. No link is
+	// rendered for the flags for which it returns an empty string. The options are then
+	// rendered as a list instead of a code block.
+	FlagGlossaryLink func(flagName string) string
+	// DescriptionData, if not nil, enables the expansion of the Short, Long and Example
+	// fields of the command as text/template templates executed against it, e.g.
+	// "{{.BinaryName}}". The expanded text is not escaped: if the data contains
+	// Markdown syntax, it is rendered as such. Literal "{{" must be written {{"{{"}}.
+	DescriptionData interface{}
+	// InheritedFlagsCollapsible wraps the options inherited from parent commands
+	// in a collapsed <details> block, keeping the page focused on the command's own options.
+	InheritedFlagsCollapsible bool
+	// IncludeDeprecated generates pages for the deprecated commands too, which
+	// begin with a banner showing the deprecation message, and links to them
+	// from the pages of their parents.
+	IncludeDeprecated bool
+	// PageTOC renders a list of links to the sections of the page below its title,
+	// on pages with at least three sections.
+	PageTOC bool
+	// LeavesOnly skips the pages of the commands which are not runnable and only group
+	// subcommands. The links to them are replaced by links to their nearest documented
+	// ancestor or descendants. Only used by GenMarkdownTreeFromOpts.
+	LeavesOnly bool
+	// ShowAliases appends the aliases of the commands listed in the SEE ALSO section
+	// to their entries, e.g. "(aliases: rm, del)". The section does not list the
+	// related commands, whose RelatedLinks are only rendered by custom templates.
+	ShowAliases bool
+	// ExampleWidth, if positive, wraps the command lines of the examples longer than
+	// this width with backslash-newline continuations. Only the lines starting with
+	// the name of the root command, optionally after a "$ " prompt, are wrapped, so
+	// that the output lines of the examples stay intact.
+	ExampleWidth int
+	// OmitInheritedFlags documents the persistent flags of the root command, the global
+	// options, on the page of the root only. The pages of the other commands link to its
+	// options instead of repeating them; the flags inherited from other parents are
+	// still listed.
+	OmitInheritedFlags bool
+	// CommandLess, if set, orders the commands listed in the SEE ALSO section of each
+	// page, returning true if a must be listed before b, e.g. to list the experimental
+	// commands after the stable ones. Unlike SetCommandSort, it does not affect the
+	// help of the commands.
+	CommandLess func(a, b *cobra.Command) bool
+	// DefaultValueFunc, if set, returns the default value rendered for each flag instead
+	// of its DefValue, e.g. "$HOME/.app/config" rather than the path computed on the
+	// machine generating the docs. A value of the zero value of the type of the flag,
+	// such as an empty string, hides the default.
+	DefaultValueFunc func(flag *pflag.Flag) string
+	// ExampleTabs, if set, renders the examples by shell of the commands, from their
+	// ExamplesByShellAnnotation, as tabs with the shortcodes of the theme of the site
+	// instead of one after the other.
+	ExampleTabs *TabShortcodes
+	// ShowFlagOrigins appends the full path of the ancestor defining each inherited
+	// flag to its help message, e.g. "config file (from app)".
+	ShowFlagOrigins bool
+}
+
+// TabShortcodes are the shortcodes rendering tabs in the theme of a site, e.g. for Hugo
+// "{{< tabs >}}", "{{< /tabs >}}", `{{< tab "%s" >}}` and "{{< /tab >}}".
+type TabShortcodes struct {
+	Begin    string // begins the tabs
+	End      string // ends the tabs
+	TabBegin string // begins a tab, with %s replaced by its label
+	TabEnd   string // ends a tab
+}
+
+// printShellExamples renders the examples of a command by shell, labeled by shell, as
+// tabs if opts.ExampleTabs is set.
+func printShellExamples(buf *bytes.Buffer, examples []ShellExample, rootName string, opts GenMarkdownOptions) {
+	tabs := opts.ExampleTabs
+	if tabs != nil {
+		buf.WriteString(tabs.Begin + "\n")
+	}
+	for _, shellExample := range examples {
+		example := shellExample.Example
+		if opts.ExampleWidth > 0 && !isWindowsShell(shellExample.Shell) {
+			example = wrapExampleLines(example, rootName, opts.ExampleWidth)
+		}
+		block := fmt.Sprintf("```%s\n%s\n```\n", shellExample.Shell, example)
+		if tabs != nil {
+			buf.WriteString(strings.Replace(tabs.TabBegin, "%s", shellExample.Shell, -1) + "\n" + block + tabs.TabEnd + "\n")
+		} else {
+			buf.WriteString("**" + shellExample.Shell + "**\n\n" + block + "\n")
+		}
+	}
+	if tabs != nil {
+		buf.WriteString(tabs.End + "\n\n")
+	}
+}
+
+// isWindowsShell returns true if shell does not support backslash-newline continuations.
+func isWindowsShell(shell string) bool {
+	switch strings.ToLower(shell) {
+	case "powershell", "pwsh", "cmd":
+		return true
+	}
+	return false
+}
+
+// flagsWithDefaults returns a copy of flags whose flags have the default values
+// returned by defaultValue, for use with DefaultValueFunc. It returns flags itself
+// if defaultValue is nil.
+func flagsWithDefaults(flags *pflag.FlagSet, defaultValue func(flag *pflag.Flag) string) *pflag.FlagSet {
+	if defaultValue == nil {
+		return flags
+	}
+	result := pflag.NewFlagSet("", pflag.ContinueOnError)
+	result.SortFlags = flags.SortFlags
+	flags.VisitAll(func(flag *pflag.Flag) {
+		flagCopy := *flag
+		flagCopy.DefValue = defaultValue(flag)
+		result.AddFlag(&flagCopy)
+	})
+	return result
+}
+
+// inheritedFlagUsages returns the usage of flags, inherited by cmd, with the origins
+// of the flags if opts.ShowFlagOrigins is set.
+func inheritedFlagUsages(cmd *cobra.Command, flags *pflag.FlagSet, opts GenMarkdownOptions) string {
+	if opts.ShowFlagOrigins {
+		return parentFlagUsages(cmd, flags)
+	}
+	return cobra.FlagUsages(flags)
+}
+
+// renderDefaultValues replaces the default values of the flags of cmdOutline by
+// those returned by opts.DefaultValueFunc.
+func renderDefaultValues(cmd *cobra.Command, cmdOutline *CmdOutline, opts GenMarkdownOptions) {
+	defaultValue := opts.DefaultValueFunc
+	flags := flagsWithDefaults(cmd.NonInheritedFlags(), defaultValue)
+	if flags.HasAvailableFlags() {
+		cmdOutline.Flags = cobra.FlagUsages(flags)
+	}
+	parentFlags := flagsWithDefaults(cmd.InheritedFlags(), defaultValue)
+	if parentFlags.HasAvailableFlags() {
+		cmdOutline.ParentFlags = inheritedFlagUsages(cmd, parentFlags, opts)
+	}
+	cmdOutline.FlagGroups = generateFlagGroupOutlines(cmd, cmd.FlagCompletionFuncs(), defaultValue)
+	for i, outline := range cmdOutline.FlagOutlines {
+		if flag := flags.Lookup(outline.Name); flag != nil {
+			cmdOutline.FlagOutlines[i].DefValue = flag.DefValue
+		}
+	}
+	for i, outline := range cmdOutline.ParentFlagOutlines {
+		if flag := parentFlags.Lookup(outline.Name); flag != nil {
+			cmdOutline.ParentFlagOutlines[i].DefValue = flag.DefValue
+		}
+	}
+}
+
+// omitGlobalFlags removes the persistent flags of the root command from the inherited
+// flags of cmdOutline, for use with OmitInheritedFlags. If any is removed, it returns
+// the sentence linking to the options of the root, which document them.
+func omitGlobalFlags(cmd *cobra.Command, cmdOutline *CmdOutline, opts GenMarkdownOptions, linkHandler func(string) string) string {
+	root := cmd.Root()
+	global := root.PersistentFlags()
+
+	remaining := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	omitted := false
+	cmd.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if global.Lookup(flag.Name) == flag {
+			omitted = true
+			return
+		}
+		remaining.AddFlag(flag)
+	})
+	if !omitted {
+		return ""
+	}
+
+	cmdOutline.ParentFlags = ""
+	if remaining.HasAvailableFlags() {
+		cmdOutline.ParentFlags = inheritedFlagUsages(cmd, flagsWithDefaults(remaining, opts.DefaultValueFunc), opts)
+	}
+	var parentFlagOutlines []FlagOutline
+	for _, flag := range cmdOutline.ParentFlagOutlines {
+		if global.Lookup(flag.Name) == nil {
+			parentFlagOutlines = append(parentFlagOutlines, flag)
+		}
+	}
+	cmdOutline.ParentFlagOutlines = parentFlagOutlines
+
+	path := root.CommandPath()
+	return fmt.Sprintf("The global options are documented on the page of [%s](%s#%s).\n\n",
+		path, linkHandler(mdDefaultLinkHandler(path)), headingAnchor("Options"))
+}
+
+// isDocumentedCommand returns true if the command is part of the documentation,
+// in which case a page is generated for it unless it is left out by LeavesOnly.
+func isDocumentedCommand(cmd *cobra.Command, opts GenMarkdownOptions) bool {
+	if opts.IncludeDeprecated && len(cmd.Deprecated) > 0 && !cmd.Hidden {
+		return cmd.Runnable() || cmd.HasAvailableSubCommands()
+	}
+	return cmd.IsAvailableCommand() && !cmd.IsAdditionalHelpTopicCommand()
+}
+
+// setLeavesOnlyRefs replaces the references to the parent and children of cmd in
+// cmdOutline by references to its nearest runnable ancestor and descendants, for use
+// with LeavesOnly.
+func setLeavesOnlyRefs(cmd *cobra.Command, cmdOutline *CmdOutline, opts GenMarkdownOptions, linkHandler func(string) string) {
+	cmdOutline.ParentLink = ""
+	for p := cmd.Parent(); p != nil; p = p.Parent() {
+		if p.Runnable() {
+			cmdOutline.ParentLink = newCommandRef(p, linkHandler, mdDefaultLinkHandler).listItem(false)
+			break
+		}
+	}
+
+	var addChildrenRefs func(c *cobra.Command)
+	addChildrenRefs = func(c *cobra.Command) {
+		children := sortedCommands(c)
+		for _, child := range children {
+			if !isDocumentedCommand(child, opts) {
+				continue
+			}
+			if child.Runnable() {
+				cmdOutline.ChildrenRefs = append(cmdOutline.ChildrenRefs, newCommandRef(child, linkHandler, mdDefaultLinkHandler))
+			} else {
+				addChildrenRefs(child)
+			}
+		}
+	}
+	cmdOutline.ChildrenRefs = nil
+	addChildrenRefs(cmd)
+}
+
+// addDeprecatedChildrenRefs adds the references to the deprecated children of cmd,
+// which generateCmdOutline leaves out, to cmdOutline. They are listed after the
+// others when the order of the children is set with SetCommandSort.
+func addDeprecatedChildrenRefs(cmd *cobra.Command, cmdOutline *CmdOutline, linkHandler func(string) string) {
+	added := false
+	for _, child := range sortedCommands(cmd) {
+		if len(child.Deprecated) == 0 || !isDocumentedCommand(child, GenMarkdownOptions{IncludeDeprecated: true}) {
+			continue
+		}
+		cmdOutline.ChildrenRefs = append(cmdOutline.ChildrenRefs, newCommandRef(child, linkHandler, mdDefaultLinkHandler))
+		added = true
+	}
+	if added && !cmd.HasCommandSort() {
+		sort.Slice(cmdOutline.ChildrenRefs, func(i, j int) bool {
+			return cmdOutline.ChildrenRefs[i].Path < cmdOutline.ChildrenRefs[j].Path
+		})
+	}
+}
+
+// sortChildrenRefs sorts the references to the children of cmd in cmdOutline, which
+// may be further descendants with LeavesOnly, with less.
+func sortChildrenRefs(cmd *cobra.Command, cmdOutline *CmdOutline, less func(a, b *cobra.Command) bool) {
+	cmds := map[string]*cobra.Command{}
+	var addDescendants func(c *cobra.Command)
+	addDescendants = func(c *cobra.Command) {
+		for _, child := range c.Commands() {
+			cmds[child.CommandPath()] = child
+			addDescendants(child)
+		}
+	}
+	addDescendants(cmd)
+
+	refs := cmdOutline.ChildrenRefs
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := cmds[refs[i].Path], cmds[refs[j].Path]
+		if a == nil || b == nil {
+			return false
+		}
+		return less(a, b)
+	})
+}
+
+// expandDescriptions expands the descriptions of cmdOutline as templates executed against data.
+func expandDescriptions(cmdOutline *CmdOutline, data interface{}) error {
+	fields := []*string{&cmdOutline.Short, &cmdOutline.Long, &cmdOutline.Example}
+	for i := range cmdOutline.ShellExamples {
+		fields = append(fields, &cmdOutline.ShellExamples[i].Example)
+	}
+	for _, field := range fields {
+		t, err := template.New(cmdOutline.Name).Parse(*field)
+		if err != nil {
+			return fmt.Errorf("parsing description of %q: %v", cmdOutline.Name, err)
+		}
+		buf := new(bytes.Buffer)
+		if err := t.Execute(buf, data); err != nil {
+			return fmt.Errorf("expanding description of %q: %v", cmdOutline.Name, err)
+		}
+		*field = buf.String()
 	}
 	return nil
 }
@@ -42,31 +472,117 @@ func GenMarkdown(cmd *cobra.Command, w io.Writer) error {
 
 // GenMarkdownCustom creates custom markdown output.
 func GenMarkdownCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string) string) error {
+	return GenMarkdownFromOpts(cmd, w, GenMarkdownOptions{LinkHandler: linkHandler})
+}
+
+// GenMarkdownFromOpts creates markdown output customized by opts.
+func GenMarkdownFromOpts(cmd *cobra.Command, w io.Writer, opts GenMarkdownOptions) error {
 	cmd.InitDefaultHelpCmd()
 	cmd.InitDefaultHelpFlag()
 
+	linkHandler := opts.LinkHandler
+	if linkHandler == nil {
+		linkHandler = func(s string) string { return s }
+	}
+
 	buf := new(bytes.Buffer)
 
-	cmdOutline := generateCmdOutline(cmd, linkHandler, mdDefaultLinkHandler)
+	cmdOutline, err := generateCmdOutline(cmd, linkHandler, mdDefaultLinkHandler)
+	if err != nil {
+		return err
+	}
+	if opts.DefaultValueFunc != nil {
+		renderDefaultValues(cmd, cmdOutline, opts)
+	} else if opts.ShowFlagOrigins && len(cmdOutline.ParentFlags) > 0 {
+		cmdOutline.ParentFlags = parentFlagUsages(cmd, cmd.InheritedFlags())
+	}
+	if opts.DescriptionData != nil {
+		if err := expandDescriptions(cmdOutline, opts.DescriptionData); err != nil {
+			return err
+		}
+	}
+	if opts.LeavesOnly {
+		setLeavesOnlyRefs(cmd, cmdOutline, opts, linkHandler)
+	} else if opts.IncludeDeprecated {
+		addDeprecatedChildrenRefs(cmd, cmdOutline, linkHandler)
+	}
+	if opts.CommandLess != nil {
+		sortChildrenRefs(cmd, cmdOutline, opts.CommandLess)
+	}
+	cmdOutline.ChildrenLinks = nil
+	for _, childRef := range cmdOutline.ChildrenRefs {
+		cmdOutline.ChildrenLinks = append(cmdOutline.ChildrenLinks, childRef.listItem(opts.ShowAliases))
+	}
 
 	buf.WriteString("## " + cmdOutline.Name + "\n\n")
+	if opts.IncludeDeprecated && len(cmd.Deprecated) > 0 {
+		buf.WriteString("> **Deprecated.** " + cmd.Deprecated + "\n\n")
+	}
+	if len(cmdOutline.Tier) > 0 {
+		buf.WriteString("**Tier:** `" + cmdOutline.Tier + "`\n\n")
+	}
+	if len(cmdOutline.Stability) > 0 {
+		buf.WriteString("**Stability:** `" + cmdOutline.Stability + "`\n\n")
+	}
+	tocPos := buf.Len()
 	buf.WriteString(cmdOutline.Short + "\n\n")
 	buf.WriteString("### Synopsis\n\n")
 	buf.WriteString(cmdOutline.Long + "\n\n")
+	if len(cmdOutline.Diagram) > 0 {
+		buf.WriteString(fmt.Sprintf("![%s](%s)\n\n", cmdOutline.Short, linkHandler(cmdOutline.Diagram)))
+	}
 
 	if cmd.Runnable() && len(cmdOutline.UseLine) > 0 {
 		buf.WriteString(fmt.Sprintf("```\n%s\n```\n\n", cmdOutline.UseLine))
 	}
+	if cmd.Runnable() && len(cmdOutline.ArgsRule) > 0 {
+		buf.WriteString(cmdOutline.ArgsRule + "\n\n")
+	}
+	if cmdOutline.HasDynamicArgs {
+		if len(cmdOutline.DynamicArgsSource) > 0 {
+			buf.WriteString("Arguments are dynamically completed (inherited from `" + cmdOutline.DynamicArgsSource + "`).\n\n")
+		} else {
+			buf.WriteString("Arguments are dynamically completed.\n\n")
+		}
+	}
 
-	if len(cmdOutline.Example) > 0 {
+	if len(cmdOutline.StdinDesc) > 0 {
+		buf.WriteString("### Input\n\n" + cmdOutline.StdinDesc + "\n\n")
+	}
+	if len(cmdOutline.StdoutDesc) > 0 {
+		buf.WriteString("### Output\n\n" + cmdOutline.StdoutDesc + "\n\n")
+	}
+	if len(cmdOutline.RequiredPermissions) > 0 {
+		buf.WriteString("### Required permissions\n\n")
+		for _, permission := range cmdOutline.RequiredPermissions {
+			buf.WriteString("* `" + permission + "`\n")
+		}
+		buf.WriteString("\n")
+	}
+
+	if len(cmdOutline.ShellExamples) > 0 {
+		buf.WriteString("### Examples\n\n")
+		printShellExamples(buf, cmdOutline.ShellExamples, cmd.Root().Name(), opts)
+	} else if len(cmdOutline.Example) > 0 {
 		buf.WriteString("### Examples\n\n")
-		buf.WriteString(fmt.Sprintf("```\n%s\n```\n\n", cmdOutline.Example))
+		example := cmdOutline.Example
+		if opts.ExampleWidth > 0 {
+			example = wrapExampleLines(example, cmd.Root().Name(), opts.ExampleWidth)
+		}
+		buf.WriteString(fmt.Sprintf("```\n%s\n```\n\n", example))
 	}
 
-	if err := printOptions(buf, cmdOutline); err != nil {
+	var globalOptionsLink string
+	if opts.OmitInheritedFlags {
+		globalOptionsLink = omitGlobalFlags(cmd, cmdOutline, opts, linkHandler)
+	}
+	if err := printOptions(buf, cmdOutline, opts, globalOptionsLink); err != nil {
 		return err
 	}
-	if len(cmdOutline.ParentLink) > 0 || len(cmdOutline.ChildrenLinks) > 0 {
+	if !cmd.HasParent() {
+		printStabilityLegend(buf, cmd, opts)
+	}
+	if len(cmdOutline.ParentLink) > 0 || len(cmdOutline.ChildrenLinks) > 0 || len(cmdOutline.ExternalLinks) > 0 {
 		buf.WriteString("### SEE ALSO\n\n")
 		if len(cmdOutline.ParentLink) > 0 {
 			buf.WriteString(cmdOutline.ParentLink)
@@ -80,22 +596,228 @@ func GenMarkdownCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string)
 		for _, childLink := range cmdOutline.ChildrenLinks {
 			buf.WriteString(childLink)
 		}
+		for _, link := range cmdOutline.ExternalLinks {
+			buf.WriteString(fmt.Sprintf("* [%s](%s)\n", link.Title, link.URL))
+		}
 		buf.WriteString("\n")
+		for _, group := range cmdOutline.ExclusiveGroups {
+			buf.WriteString("Only one of `" + strings.Join(group, "`, `") + "` can be used at a time.\n\n")
+		}
 	}
 
+	if opts.PageTOC {
+		insertPageTOC(buf, tocPos)
+	}
 	if !cmd.DisableAutoGenTag {
 		buf.WriteString("######" + cmdOutline.AutoGenTag)
 	}
-	_, err := buf.WriteTo(w)
+	_, err = buf.WriteTo(w)
 	return err
 }
 
+// printStabilityLegend describes the stability levels of the documented commands of the
+// tree of root, for its page, which is the index of the documentation.
+func printStabilityLegend(buf *bytes.Buffer, root *cobra.Command, opts GenMarkdownOptions) {
+	used := map[string]bool{}
+	var visit func(c *cobra.Command)
+	visit = func(c *cobra.Command) {
+		used[stability(c)] = true
+		for _, child := range c.Commands() {
+			if isDocumentedCommand(child, opts) {
+				visit(child)
+			}
+		}
+	}
+	visit(root)
+
+	var legend []string
+	for _, level := range stabilityLevels {
+		if used[level.Name] {
+			legend = append(legend, "* `"+level.Name+"`: "+level.Description+"\n")
+		}
+	}
+	if len(legend) == 0 {
+		return
+	}
+	buf.WriteString("### Stability levels\n\n" + strings.Join(legend, "") + "\n")
+}
+
+// pageTOCMinSections is the number of sections from which a page gets a table of contents.
+const pageTOCMinSections = 3
+
+// insertPageTOC inserts at pos in buf a list of links to the sections of the page
+// which follow pos, if there are at least pageTOCMinSections of them.
+func insertPageTOC(buf *bytes.Buffer, pos int) {
+	var sections []string
+	inCode := false
+	for _, line := range strings.Split(buf.String()[pos:], "\n") {
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+		}
+		if !inCode && strings.HasPrefix(line, "### ") {
+			sections = append(sections, strings.TrimPrefix(line, "### "))
+		}
+	}
+	if len(sections) < pageTOCMinSections {
+		return
+	}
+
+	toc := new(bytes.Buffer)
+	for _, section := range sections {
+		toc.WriteString(fmt.Sprintf("* [%s](#%s)\n", section, headingAnchor(section)))
+	}
+	toc.WriteString("\n")
+
+	rest := append([]byte(nil), buf.Bytes()[pos:]...)
+	buf.Truncate(pos)
+	toc.WriteTo(buf)
+	buf.Write(rest)
+}
+
+// headingAnchor returns the anchor generated for a heading by GitHub and most
+// Markdown renderers: lowercase, with punctuation removed and spaces replaced by hyphens.
+func headingAnchor(heading string) string {
+	var anchor strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ':
+			anchor.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			anchor.WriteRune(r)
+		}
+	}
+	return anchor.String()
+}
+
+// wrapExampleLines wraps the command lines of example, those starting with rootName,
+// optionally after a "$ " prompt, which are longer than width. The lines are broken
+// between words, outside of quotes, with backslash-newline continuations, and the
+// continued lines are indented by two more spaces than the command line. A flag is
+// kept on the line of the value following it.
+func wrapExampleLines(example, rootName string, width int) string {
+	lines := strings.Split(example, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		command := strings.TrimPrefix(trimmed, "$ ")
+		if len(line) <= width || !(command == rootName || strings.HasPrefix(command, rootName+" ")) {
+			continue
+		}
+
+		indent := line[:len(line)-len(trimmed)] + "  "
+		var wrapped strings.Builder
+		current := line[:len(line)-len(trimmed)]
+		words := exampleWords(trimmed)
+		var units []string
+		for j := 0; j < len(words); j++ {
+			word := words[j]
+			if strings.HasPrefix(word, "-") && !strings.Contains(word, "=") &&
+				j+1 < len(words) && !strings.HasPrefix(words[j+1], "-") {
+				j++
+				word += " " + words[j]
+			}
+			units = append(units, word)
+		}
+		for j, word := range units {
+			if j > 0 && len(current)+1+len(word)+2 > width {
+				wrapped.WriteString(current + " \\\n")
+				current = indent + word
+				continue
+			}
+			if j > 0 {
+				current += " "
+			}
+			current += word
+		}
+		wrapped.WriteString(current)
+		lines[i] = wrapped.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exampleWords splits a command line into words, keeping the quoted strings and the
+// escaped spaces in their words.
+func exampleWords(line string) []string {
+	var (
+		words   []string
+		word    strings.Builder
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ' ' || r == '\t':
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(r)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
 func mdDefaultLinkHandler(name string) string {
 	link := name + ".md"
 	link = strings.Replace(link, " ", "_", -1)
 	return link
 }
 
+// DocusaurusFrontMatter returns the front matter of the page of cmd for use with
+// Docusaurus, whose id matches the name of the generated file, e.g.:
+//
+//	---
+//	id: root_echo
+//	title: 'Echo anything to the screen'
+//	sidebar_label: echo
+//	sidebar_position: 2
+//	---
+//
+// The title is the short description of the command, or its path if it has none,
+// and the sidebar position is the position of the command among its documented
+// siblings, in the order of the SEE ALSO section of its parent, starting at 1.
+// It can be set as GenMarkdownOptions.FrontMatter.
+func DocusaurusFrontMatter(cmd *cobra.Command) string {
+	title := cmd.Short
+	if len(title) == 0 {
+		title = cmd.CommandPath()
+	}
+	position := 1
+	if cmd.HasParent() {
+		for _, sibling := range sortedCommands(cmd.Parent()) {
+			if sibling == cmd {
+				break
+			}
+			if sibling.IsAvailableCommand() && !sibling.IsAdditionalHelpTopicCommand() {
+				position++
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("---\n")
+	buf.WriteString("id: " + strings.Replace(cmd.CommandPath(), " ", "_", -1) + "\n")
+	// YAML single-quoted scalars have no escapes but the doubled quote.
+	buf.WriteString("title: '" + strings.Replace(title, "'", "''", -1) + "'\n")
+	buf.WriteString("sidebar_label: " + cmd.Name() + "\n")
+	buf.WriteString(fmt.Sprintf("sidebar_position: %d\n", position))
+	buf.WriteString("---\n\n")
+	return buf.String()
+}
+
 // GenMarkdownTree will generate a markdown page for this command and all
 // descendants in the directory given. The header may be nil.
 // This function may not work correctly if your command names have `-` in them.
@@ -111,28 +833,79 @@ func GenMarkdownTree(cmd *cobra.Command, dir string) error {
 // GenMarkdownTreeCustom is the the same as GenMarkdownTree, but
 // with custom filePrepender and linkHandler.
 func GenMarkdownTreeCustom(cmd *cobra.Command, dir string, filePrepender, linkHandler func(string) string) error {
+	return GenMarkdownTreeFromOpts(cmd, GenMarkdownOptions{
+		Path:          dir,
+		FilePrepender: filePrepender,
+		LinkHandler:   linkHandler,
+	})
+}
+
+// GenMarkdownTreeFromOpts generates a markdown page for the command and all descendants.
+// The pages are written to the opts.Path directory.
+func GenMarkdownTreeFromOpts(cmd *cobra.Command, opts GenMarkdownOptions) error {
 	for _, c := range cmd.Commands() {
-		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+		if !isDocumentedCommand(c, opts) {
 			continue
 		}
-		if err := GenMarkdownTreeCustom(c, dir, filePrepender, linkHandler); err != nil {
+		if err := GenMarkdownTreeFromOpts(c, opts); err != nil {
 			return err
 		}
 	}
+	if opts.LeavesOnly && !cmd.Runnable() {
+		return nil
+	}
 
 	basename := strings.Replace(cmd.CommandPath(), " ", "_", -1) + ".md"
-	filename := filepath.Join(dir, basename)
+	filename := filepath.Join(opts.Path, basename)
 	f, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	if _, err := io.WriteString(f, filePrepender(filename)); err != nil {
-		return err
+	if opts.FilePrepender != nil {
+		if _, err := io.WriteString(f, opts.FilePrepender(filename)); err != nil {
+			return err
+		}
+	}
+	if opts.FrontMatter != nil {
+		if _, err := io.WriteString(f, opts.FrontMatter(cmd)); err != nil {
+			return err
+		}
 	}
-	if err := GenMarkdownCustom(cmd, f, linkHandler); err != nil {
+	if err := GenMarkdownFromOpts(cmd, f, opts); err != nil {
 		return err
 	}
 	return nil
 }
+
+// GenMultiRootTree generates the markdown pages of several independent command
+// trees, e.g. the different binaries of a product, into a single site.
+// The pages of each root are written to a subdirectory of dir named after the
+// key of the root in roots, so that each root keeps its own entry page.
+// The linkHandler receives the name of the root whose pages are being generated
+// along with the link target, which allows cross-linking between binaries.
+// It may be nil to keep links relative to the subdirectory of each root.
+func GenMultiRootTree(roots map[string]*cobra.Command, dir string, linkHandler func(rootName string, target string) string) error {
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rootName := name
+		path := filepath.Join(dir, rootName)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return err
+		}
+		opts := GenMarkdownOptions{Path: path}
+		if linkHandler != nil {
+			opts.LinkHandler = func(target string) string { return linkHandler(rootName, target) }
+		}
+		if err := GenMarkdownTreeFromOpts(roots[rootName], opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
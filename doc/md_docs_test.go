@@ -5,9 +5,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func TestGenMdDoc(t *testing.T) {
@@ -81,6 +84,477 @@ func TestGenMdTree(t *testing.T) {
 	}
 }
 
+type enumValue string
+
+func (e *enumValue) String() string     { return string(*e) }
+func (e *enumValue) Set(v string) error { *e = enumValue(v); return nil }
+func (e *enumValue) Type() string       { return "format" }
+
+func TestGenMdTypeLinks(t *testing.T) {
+	c := &cobra.Command{Use: "do", Run: emptyRun}
+	c.Flags().Duration("timeout", time.Second, "time to wait")
+	format := enumValue("json")
+	c.Flags().Var(&format, "output", "output format")
+	c.Flags().Bool("force", false, "force it")
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownFromOpts(c, buf, GenMarkdownOptions{
+		TypeLinkHandler: func(typeName string) string { return "#type-" + typeName },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "* `--timeout` [duration](#type-duration): time to wait (default 1s)")
+	checkStringContains(t, output, "* `--output` [format](#type-format): output format (default json)")
+	checkStringContains(t, output, "* `--force`: force it\n")
+	checkStringOmits(t, output, "[bool]")
+}
+
+func TestGenMultiRootTree(t *testing.T) {
+	server := &cobra.Command{Use: "server", Short: "The server"}
+	server.AddCommand(&cobra.Command{Use: "start", Short: "Start the server", Run: emptyRun})
+	client := &cobra.Command{Use: "client", Short: "The client", Run: emptyRun}
+
+	tmpdir, err := ioutil.TempDir("", "test-gen-multi-root-tree")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	linkHandler := func(rootName, target string) string {
+		return "/" + rootName + "/" + target
+	}
+	roots := map[string]*cobra.Command{"srv": server, "cli": client}
+	if err := GenMultiRootTree(roots, tmpdir, linkHandler); err != nil {
+		t.Fatalf("GenMultiRootTree failed: %v", err)
+	}
+
+	for _, file := range []string{"srv/server.md", "srv/server_start.md", "cli/client.md"} {
+		if _, err := os.Stat(filepath.Join(tmpdir, file)); err != nil {
+			t.Fatalf("Expected file %q to exist", file)
+		}
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(tmpdir, "srv", "server.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, string(content), "[server start](/srv/server_start.md)")
+}
+
+func TestGenMdDescriptionData(t *testing.T) {
+	c := &cobra.Command{
+		Use:     "do",
+		Short:   "Run {{.BinaryName}}",
+		Long:    "Reads its configuration from {{.ConfigPath}}.",
+		Example: "{{.BinaryName}} do --config {{.ConfigPath}}",
+		Run:     emptyRun,
+	}
+	data := struct{ BinaryName, ConfigPath string }{"myapp", "$HOME/.myapp"}
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownFromOpts(c, buf, GenMarkdownOptions{DescriptionData: data}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "Run myapp")
+	checkStringContains(t, output, "Reads its configuration from $HOME/.myapp.")
+	checkStringContains(t, output, "myapp do --config $HOME/.myapp")
+
+	// Without data, the descriptions are rendered literally
+	buf.Reset()
+	if err := GenMarkdown(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "Run {{.BinaryName}}")
+
+	c.Long = "{{.Unknown}}"
+	if err := GenMarkdownFromOpts(c, buf, GenMarkdownOptions{DescriptionData: data}); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
+
+func TestGenMdFlagDependencies(t *testing.T) {
+	c := &cobra.Command{Use: "do", Run: emptyRun}
+	c.Flags().String("tls-cert", "", "certificate file")
+	c.Flags().Bool("tls", false, "use TLS")
+	if err := c.SetFlagDependency("tls-cert", "tls", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "Setting `--tls-cert` implies `--tls=true`.")
+
+	buf.Reset()
+	if err := GenMarkdownFromOpts(c, buf, GenMarkdownOptions{
+		TypeLinkHandler: func(typeName string) string { return "#" + typeName },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "certificate file (implies `--tls=true`)")
+}
+
+func TestGenMdInheritedFlagsCollapsible(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownFromOpts(echoCmd, buf, GenMarkdownOptions{InheritedFlagsCollapsible: true}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "### Options\n\n```\n")
+	checkStringContains(t, output, "<details>\n<summary>Options inherited from parent commands</summary>\n\n```\n")
+	checkStringOmits(t, output, "### Options inherited from parent commands")
+
+	details := output[strings.Index(output, "<details>"):strings.Index(output, "</details>")]
+	checkStringContains(t, details, "rootflag")
+	checkStringOmits(t, details, "boolone")
+}
+
+func TestGenMdIncludeDeprecated(t *testing.T) {
+	root := &cobra.Command{Use: "root", Short: "Root short description", Run: emptyRun}
+	old := &cobra.Command{
+		Use:        "old",
+		Short:      "Old short description",
+		Deprecated: "use new instead",
+		Run:        emptyRun,
+	}
+	root.AddCommand(old)
+
+	tmpdir, err := ioutil.TempDir("", "test-gen-md-deprecated")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := GenMarkdownTreeFromOpts(root, GenMarkdownOptions{Path: tmpdir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpdir, "root_old.md")); !os.IsNotExist(err) {
+		t.Fatalf("Expected no page for the deprecated command by default")
+	}
+
+	if err := GenMarkdownTreeFromOpts(root, GenMarkdownOptions{Path: tmpdir, IncludeDeprecated: true}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(tmpdir, "root_old.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "## root old\n\n> **Deprecated.** use new instead\n\nOld short description\n\n"
+	if !strings.HasPrefix(string(content), expected) {
+		t.Errorf("Expected page to begin with:\n%q\nGot:\n%q", expected, content)
+	}
+
+	content, err = ioutil.ReadFile(filepath.Join(tmpdir, "root.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, string(content), "* [root old](root_old.md)\t - Old short description\n")
+}
+
+func TestDocusaurusFrontMatter(t *testing.T) {
+	expected := "---\n" +
+		"id: root_echo_times\n" +
+		"title: 'Echo anything to the screen more times'\n" +
+		"sidebar_label: times\n" +
+		"sidebar_position: 2\n" +
+		"---\n\n"
+	if got := DocusaurusFrontMatter(timesCmd); got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{Use: "get", Short: `Get a resource's "état" \u00e9`, Run: emptyRun}
+	root.AddCommand(&cobra.Command{Use: "create", Run: emptyRun}, get)
+	got := DocusaurusFrontMatter(get)
+	checkStringContains(t, got, "title: 'Get a resource''s \"état\" \\u00e9'\n")
+	checkStringContains(t, got, "sidebar_position: 2\n")
+}
+
+func TestGenMdTreeFrontMatter(t *testing.T) {
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	root.AddCommand(&cobra.Command{Use: "child", Short: "Child short description", Run: emptyRun})
+
+	tmpdir, err := ioutil.TempDir("", "test-gen-md-front-matter")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	opts := GenMarkdownOptions{
+		Path:          tmpdir,
+		FilePrepender: func(string) string { return "<!-- generated -->\n" },
+		FrontMatter:   DocusaurusFrontMatter,
+	}
+	if err := GenMarkdownTreeFromOpts(root, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(tmpdir, "root_child.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<!-- generated -->\n---\nid: root_child\ntitle: 'Child short description'\n"
+	if !strings.HasPrefix(string(content), expected) {
+		t.Errorf("Expected page to begin with:\n%q\nGot:\n%q", expected, content)
+	}
+
+	content, err = ioutil.ReadFile(filepath.Join(tmpdir, "root.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, string(content), "title: 'root'\n")
+}
+
+func TestGenMdPageTOC(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownFromOpts(echoCmd, buf, GenMarkdownOptions{PageTOC: true}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	expected := "## root echo\n\n" +
+		"* [Synopsis](#synopsis)\n" +
+		"* [Examples](#examples)\n" +
+		"* [Options](#options)\n" +
+		"* [Options inherited from parent commands](#options-inherited-from-parent-commands)\n" +
+		"* [SEE ALSO](#see-also)\n\n" +
+		echoCmd.Short + "\n\n"
+	if !strings.HasPrefix(output, expected) {
+		t.Errorf("Expected page to begin with:\n%q\nGot:\n%q", expected, output)
+	}
+
+	// Short pages do not get a table of contents.
+	c := &cobra.Command{Use: "do", Short: "Do something", Run: emptyRun}
+	buf.Reset()
+	if err := GenMarkdownFromOpts(c, buf, GenMarkdownOptions{PageTOC: true}); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "(#synopsis)")
+}
+
+func TestGenMdDiagram(t *testing.T) {
+	c := &cobra.Command{
+		Use:         "deploy",
+		Short:       "Deploy the application",
+		Long:        "Deploy the application to the cluster.",
+		Annotations: map[string]string{DiagramAnnotation: "images/deploy.svg"},
+		Run:         emptyRun,
+	}
+
+	buf := new(bytes.Buffer)
+	opts := GenMarkdownOptions{LinkHandler: func(s string) string { return "/docs/" + s }}
+	if err := GenMarkdownFromOpts(c, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), c.Long+"\n\n![Deploy the application](/docs/images/deploy.svg)\n\n")
+
+	buf.Reset()
+	if err := GenMarkdownFromOpts(echoCmd, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "![")
+}
+
+func TestGenMdTranslatedFlagTypeNames(t *testing.T) {
+	defer func(names map[string]string) { cobra.FlagTypeNames = names }(cobra.FlagTypeNames)
+	cobra.FlagTypeNames = map[string]string{"int": "entier"}
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(echoCmd, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "--intone entier")
+
+	buf.Reset()
+	opts := GenMarkdownOptions{TypeLinkHandler: func(typeName string) string { return "#type-" + typeName }}
+	if err := GenMarkdownFromOpts(echoCmd, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* `-i, --intone` [entier](#type-int)")
+}
+
+func TestGenMdTreeLeavesOnly(t *testing.T) {
+	root := &cobra.Command{Use: "root", Short: "Root short description"}
+	group := &cobra.Command{Use: "group", Short: "Group short description"}
+	leaf := &cobra.Command{Use: "leaf", Short: "Leaf short description", Run: emptyRun}
+	other := &cobra.Command{Use: "other", Short: "Other short description", Run: emptyRun}
+	group.AddCommand(leaf)
+	root.AddCommand(group, other)
+
+	tmpdir, err := ioutil.TempDir("", "test-gen-md-leaves-only")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := GenMarkdownTreeFromOpts(root, GenMarkdownOptions{Path: tmpdir, LeavesOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"root.md", "root_group.md"} {
+		if _, err := os.Stat(filepath.Join(tmpdir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected no page %q for a command grouping subcommands", name)
+		}
+	}
+	content, err := ioutil.ReadFile(filepath.Join(tmpdir, "root_group_leaf.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, string(content), "### SEE ALSO")
+	if _, err := os.Stat(filepath.Join(tmpdir, "root_other.md")); err != nil {
+		t.Errorf("Expected page for the runnable command: %v", err)
+	}
+
+	// Links skip to the nearest documented ancestor and descendants.
+	root.Run = emptyRun
+	if err := GenMarkdownTreeFromOpts(root, GenMarkdownOptions{Path: tmpdir, LeavesOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	content, err = ioutil.ReadFile(filepath.Join(tmpdir, "root_group_leaf.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, string(content), "* [root](root.md)\t - Root short description\n")
+	content, err = ioutil.ReadFile(filepath.Join(tmpdir, "root.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, string(content), "* [root group leaf](root_group_leaf.md)\t - Leaf short description\n"+
+		"* [root other](root_other.md)\t - Other short description\n")
+	checkStringOmits(t, string(content), "root_group.md")
+}
+
+func TestGenMdShowAliases(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownFromOpts(rootCmd, buf, GenMarkdownOptions{ShowAliases: true}); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* [root echo](root_echo.md)\t - Echo anything to the screen (aliases: say)\n")
+	checkStringContains(t, buf.String(), "* [root print](root_print.md)\t - Print anything to the screen\n")
+
+	buf.Reset()
+	if err := GenMarkdownFromOpts(rootCmd, buf, GenMarkdownOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "aliases:")
+}
+
+func TestGenMdShowDeprecatedAliases(t *testing.T) {
+	root := &cobra.Command{Use: "root", Short: "Root short description", Run: emptyRun}
+	remove := &cobra.Command{Use: "remove", Aliases: []string{"rm", "del"}, Short: "Remove a resource", Run: emptyRun}
+	root.AddCommand(remove)
+	if err := remove.MarkAliasDeprecated("del", "use rm instead"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownFromOpts(root, buf, GenMarkdownOptions{ShowAliases: true}); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* [root remove](root_remove.md)\t - Remove a resource (aliases: rm, del (deprecated))\n")
+}
+
+func TestGenMdTier(t *testing.T) {
+	root := &cobra.Command{Use: "root", Short: "Root short description", Run: emptyRun}
+	audit := &cobra.Command{
+		Use:         "audit",
+		Short:       "Audit the accounts",
+		Annotations: map[string]string{TierAnnotation: "enterprise"},
+		Run:         emptyRun,
+	}
+	root.AddCommand(audit)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(audit, buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "## root audit\n\n**Tier:** `enterprise`\n\nAudit the accounts\n\n"
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("Expected page to begin with:\n%q\nGot:\n%q", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := GenMarkdown(root, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* [root audit](root_audit.md)\t - Audit the accounts `enterprise`\n")
+	checkStringOmits(t, buf.String(), "**Tier:**")
+}
+
+func TestGenMdStability(t *testing.T) {
+	root := &cobra.Command{Use: "root", Short: "Root short description", Run: emptyRun}
+	sync := &cobra.Command{
+		Use:         "sync",
+		Short:       "Sync the accounts",
+		Annotations: map[string]string{StabilityAnnotation: "beta"},
+		Run:         emptyRun,
+	}
+	get := &cobra.Command{
+		Use:         "get",
+		Annotations: map[string]string{StabilityAnnotation: "stable"},
+		Run:         emptyRun,
+	}
+	legacy := &cobra.Command{
+		Use:         "legacy",
+		Annotations: map[string]string{StabilityAnnotation: "frozen"},
+		Run:         emptyRun,
+	}
+	debug := &cobra.Command{
+		Use:         "debug",
+		Hidden:      true,
+		Annotations: map[string]string{StabilityAnnotation: "alpha"},
+		Run:         emptyRun,
+	}
+	root.AddCommand(sync, get, legacy, debug)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(sync, buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "## root sync\n\n**Stability:** `beta`\n\nSync the accounts\n\n"
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("Expected page to begin with:\n%q\nGot:\n%q", expected, buf.String())
+	}
+	checkStringOmits(t, buf.String(), "### Stability levels")
+
+	buf.Reset()
+	if err := GenMarkdown(legacy, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "**Stability:**")
+
+	// The legend on the page of the root describes the levels of the documented commands.
+	buf.Reset()
+	if err := GenMarkdown(root, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+	checkStringOmits(t, output, "**Stability:**")
+	checkStringContains(t, output, "### Stability levels\n\n"+
+		"* `stable`: The command is supported and only changes in backward-compatible ways.\n"+
+		"* `beta`: The command is complete but may still change in incompatible ways.\n\n")
+	checkStringOmits(t, output, "`alpha`")
+	checkStringOmits(t, output, "frozen")
+}
+
+func TestGenMdRawUseLine(t *testing.T) {
+	c := &cobra.Command{Use: "sync (--all | <source>...) <destination>", Run: emptyRun}
+	c.Flags().Bool("all", false, "")
+	c.SetRawUseLine(true)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "```\nsync (--all | <source>...) <destination>\n```\n")
+}
+
 func BenchmarkGenMarkdownToFile(b *testing.B) {
 	file, err := ioutil.TempFile("", "")
 	if err != nil {
@@ -96,3 +570,492 @@ func BenchmarkGenMarkdownToFile(b *testing.B) {
 		}
 	}
 }
+
+func TestGenMdExampleWidth(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	deploy := &cobra.Command{
+		Use: "deploy",
+		Example: `  $ app deploy --environment production --region eu-west-1 --message "ship the release"
+  Deploying to production in eu-west-1, with a line of output longer than the width`,
+		Run: emptyRun,
+	}
+	root.AddCommand(deploy)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownFromOpts(deploy, buf, GenMarkdownOptions{ExampleWidth: 50}); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "```\n"+
+		"  $ app deploy --environment production \\\n"+
+		"    --region eu-west-1 \\\n"+
+		"    --message \"ship the release\"\n"+
+		"  Deploying to production in eu-west-1, with a line of output longer than the width\n"+
+		"```\n")
+
+	buf.Reset()
+	if err := GenMarkdownFromOpts(deploy, buf, GenMarkdownOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), `  $ app deploy --environment production --region eu-west-1 --message "ship the release"`+"\n")
+}
+
+func TestGenMdExclusiveCommandGroup(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	root.AddCommand(
+		&cobra.Command{Use: "encrypt", Short: "Encrypt a file", Run: emptyRun},
+		&cobra.Command{Use: "decrypt", Short: "Decrypt a file", Run: emptyRun},
+	)
+	if err := root.AddExclusiveCommandGroup("encrypt", "decrypt"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(root, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* [app encrypt](app_encrypt.md)\t - Encrypt a file\n\n"+
+		"Only one of `app encrypt`, `app decrypt` can be used at a time.\n\n")
+}
+
+func TestGenMdDynamicCompletion(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{Use: "get", Run: emptyRun}
+	root.AddCommand(get)
+	get.Flags().String("namespace", "", "namespace of the resource")
+	get.Flags().String("output", "", "output format")
+	_ = get.RegisterFlagCompletionFunc("namespace", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(get, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "Dynamic completion is available for `--namespace`.\n")
+
+	buf.Reset()
+	opts := GenMarkdownOptions{TypeLinkHandler: func(string) string { return "#types" }}
+	if err := GenMarkdownFromOpts(get, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* `--namespace` [string](#types): namespace of the resource (dynamic completion available)\n")
+	checkStringContains(t, buf.String(), "* `--output` [string](#types): output format\n")
+}
+
+func TestGenMdInputAndOutput(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	convert := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert records",
+		Run:   emptyRun,
+		Annotations: map[string]string{
+			StdinAnnotation:  "CSV records, with a header line",
+			StdoutAnnotation: "JSON lines, one record per line",
+		},
+	}
+	root.AddCommand(convert)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(convert, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "```\n\n### Input\n\nCSV records, with a header line\n\n"+
+		"### Output\n\nJSON lines, one record per line\n\n")
+
+	buf.Reset()
+	if err := GenMarkdown(root, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "### Input")
+	checkStringOmits(t, buf.String(), "### Output")
+}
+
+func TestGenMdTreeOmitInheritedFlags(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	root.PersistentFlags().String("context", "", "context to use")
+	config := &cobra.Command{Use: "config", Run: emptyRun}
+	config.PersistentFlags().String("file", "", "configuration file")
+	view := &cobra.Command{Use: "view", Run: emptyRun}
+	config.AddCommand(view)
+	root.AddCommand(config)
+
+	tmpdir, err := ioutil.TempDir("", "test-gen-md-tree")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := GenMarkdownTreeFromOpts(root, GenMarkdownOptions{Path: tmpdir, OmitInheritedFlags: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	rootPage, err := ioutil.ReadFile(filepath.Join(tmpdir, "app.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, string(rootPage), "### Options\n\n```\n      --context string   context to use\n")
+	checkStringOmits(t, string(rootPage), "The global options")
+
+	viewPage, err := ioutil.ReadFile(filepath.Join(tmpdir, "app_config_view.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, string(viewPage), "### Options inherited from parent commands\n\n```\n      --file string   configuration file\n```\n\n"+
+		"The global options are documented on the page of [app](app.md#options).\n\n")
+	checkStringOmits(t, string(viewPage), "--context")
+}
+
+func TestGenMdLongFuncAndExampleFunc(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	plugin := &cobra.Command{
+		Use:         "plugin",
+		Short:       "Run a plugin",
+		LongFunc:    func(*cobra.Command) string { return "Installed plugins: foo, bar" },
+		ExampleFunc: func(*cobra.Command) string { return "  app plugin foo" },
+		Run:         emptyRun,
+	}
+	root.AddCommand(plugin)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(plugin, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "### Synopsis\n\nInstalled plugins: foo, bar\n\n")
+	checkStringContains(t, buf.String(), "### Examples\n\n```\n  app plugin foo\n```\n")
+}
+
+func TestGenMdFlagGlossaryLink(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	root.PersistentFlags().StringP("output", "o", "", "output format")
+	get := &cobra.Command{Use: "get", Run: emptyRun}
+	get.Flags().Bool("watch", false, "watch for changes")
+	root.AddCommand(get)
+
+	opts := GenMarkdownOptions{FlagGlossaryLink: func(flagName string) string {
+		if flagName == "output" {
+			return "glossary.md#flag-output"
+		}
+		return ""
+	}}
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownFromOpts(get, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* [`-o, --output`](glossary.md#flag-output) string: output format\n")
+	checkStringContains(t, buf.String(), "* `--watch`: watch for changes\n")
+}
+
+func TestGenMdRequiredPermissions(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	deploy := &cobra.Command{
+		Use:         "deploy",
+		Short:       "Deploy the application",
+		Annotations: map[string]string{RBACAnnotation: "deployments:write, pods:read\nsecrets:read"},
+		Run:         emptyRun,
+	}
+	root.AddCommand(deploy)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(deploy, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "### Required permissions\n\n* `deployments:write`\n* `pods:read`\n* `secrets:read`\n\n")
+
+	buf.Reset()
+	if err := GenMarkdown(root, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "### Required permissions")
+}
+
+func TestGenMdDynamicArgs(t *testing.T) {
+	root := &cobra.Command{
+		Use: "app",
+		PersistentValidArgsFunction: func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	get := &cobra.Command{Use: "get", Run: emptyRun}
+	list := &cobra.Command{Use: "list", ValidArgs: []string{"pods"}, Run: emptyRun}
+	root.AddCommand(get, list)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(get, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "Arguments are dynamically completed (inherited from `app`).\n")
+
+	buf.Reset()
+	if err := GenMarkdown(list, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "dynamically completed")
+}
+
+func TestGenMdExternalLinks(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{
+		Use:   "get",
+		Short: "Fetch a resource",
+		Annotations: map[string]string{
+			ExternalLinksAnnotation: `[{"title": "RFC 7231", "url": "https://www.rfc-editor.org/rfc/rfc7231"}]`,
+		},
+		Run: emptyRun,
+	}
+	root.AddCommand(get)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(get, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "### SEE ALSO\n\n"+
+		"* [app](app.md)\t - \n"+
+		"* [RFC 7231](https://www.rfc-editor.org/rfc/rfc7231)\n\n")
+
+	buf.Reset()
+	if err := GenMarkdown(root, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "RFC 7231")
+
+	get.Annotations[ExternalLinksAnnotation] = `{"title": "RFC 7231"}`
+	if err := GenMarkdown(get, new(bytes.Buffer)); err == nil {
+		t.Error("expected an error for an external_links annotation which is not an array")
+	}
+}
+
+func TestGenMdCommandSort(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	for _, name := range []string{"init", "build", "deploy"} {
+		root.AddCommand(&cobra.Command{Use: name, Run: emptyRun})
+	}
+	root.SetCommandSort(func(cmds []*cobra.Command) []*cobra.Command {
+		return cmds
+	})
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(root, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* [app init](app_init.md)\t - \n"+
+		"* [app build](app_build.md)\t - \n"+
+		"* [app deploy](app_deploy.md)\t - \n")
+}
+
+func TestGenMdCommandLess(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	root.AddCommand(
+		&cobra.Command{Use: "alpha", Annotations: map[string]string{"stability": "experimental"}, Run: emptyRun},
+		&cobra.Command{Use: "get", Run: emptyRun},
+		&cobra.Command{Use: "set", Run: emptyRun},
+	)
+	experimental := func(c *cobra.Command) bool { return c.Annotations["stability"] == "experimental" }
+
+	buf := new(bytes.Buffer)
+	opts := GenMarkdownOptions{
+		CommandLess: func(a, b *cobra.Command) bool {
+			if experimental(a) != experimental(b) {
+				return !experimental(a)
+			}
+			return a.Name() < b.Name()
+		},
+	}
+	if err := GenMarkdownFromOpts(root, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* [app get](app_get.md)\t - \n"+
+		"* [app set](app_set.md)\t - \n"+
+		"* [app alpha](app_alpha.md)\t - \n")
+
+	// The help is not affected.
+	if root.Commands()[0].Name() != "alpha" {
+		t.Errorf("Expected the help to list alpha first, got %s", root.Commands()[0].Name())
+	}
+}
+
+func TestGenMdDefaultValueFunc(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{Use: "get", Run: emptyRun}
+	root.AddCommand(get)
+	root.PersistentFlags().String("config", "/home/ci/.app/config", "config file")
+	get.Flags().String("cache-dir", "/home/ci/.cache/app", "cache directory")
+	get.Flags().Int("limit", 10, "maximum number of results")
+
+	opts := GenMarkdownOptions{
+		DefaultValueFunc: func(flag *pflag.Flag) string {
+			return strings.Replace(flag.DefValue, "/home/ci", "$HOME", 1)
+		},
+	}
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownFromOpts(get, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, `cache directory (default "$HOME/.cache/app")`)
+	checkStringContains(t, output, `config file (default "$HOME/.app/config")`)
+	checkStringContains(t, output, "maximum number of results (default 10)")
+	checkStringOmits(t, output, "/home/ci")
+
+	// The flags themselves are not modified.
+	if def := get.Flag("cache-dir").DefValue; def != "/home/ci/.cache/app" {
+		t.Errorf("Expected the default value of the flag to be unchanged, got %q", def)
+	}
+
+	opts.TypeLinkHandler = func(typeName string) string { return "#" + typeName }
+	buf.Reset()
+	if err := GenMarkdownFromOpts(get, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), `cache directory (default "$HOME/.cache/app")`)
+	checkStringOmits(t, buf.String(), "/home/ci")
+}
+
+func TestGenMdArgsRule(t *testing.T) {
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	copyCmd := &cobra.Command{Use: "copy <src> <dst>", Args: cobra.ExactArgs(2), Run: emptyRun}
+	custom := &cobra.Command{
+		Use:  "custom",
+		Args: func(cmd *cobra.Command, args []string) error { return nil },
+		Run:  emptyRun,
+	}
+	root.AddCommand(copyCmd, custom)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(copyCmd, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "```\nroot copy <src> <dst> [flags]\n```\n\nRequires exactly 2 arguments.\n\n")
+
+	buf.Reset()
+	if err := GenMarkdown(custom, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "arguments.")
+}
+
+func TestGenMdFlagGroups(t *testing.T) {
+	c := &cobra.Command{Use: "connect", Run: emptyRun}
+	c.Flags().String("host", "", "server host")
+	c.Flags().Int("port", 443, "server port")
+	c.Flags().Bool("verbose", false, "verbose output")
+	if err := c.AddFlagGroup("Connection", "host", "port"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+	checkStringContains(t, output, "### Options\n\n```\n"+
+		"  -h, --help      help for connect\n"+
+		"      --verbose   verbose output\n```\n\n"+
+		"### Connection options\n\n```\n"+
+		"      --host string   server host\n"+
+		"      --port int      server port (default 443)\n```\n\n")
+
+	buf.Reset()
+	opts := GenMarkdownOptions{TypeLinkHandler: func(typeName string) string { return "#" + typeName }}
+	if err := GenMarkdownFromOpts(c, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "### Connection options\n\n* `--host` [string](#string): server host\n")
+}
+
+func TestGenMdInheritedFlagOrigins(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{Use: "get", Run: emptyRun}
+	pods := &cobra.Command{Use: "pods", Run: emptyRun}
+	root.AddCommand(get)
+	get.AddCommand(pods)
+	root.PersistentFlags().String("config", "", "config file")
+	get.PersistentFlags().String("namespace", "", "namespace of the resources")
+	pods.Flags().Bool("all", false, "list all the pods")
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(pods, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "--config string      config file\n")
+	checkStringOmits(t, buf.String(), "(from ")
+
+	buf.Reset()
+	if err := GenMarkdownFromOpts(pods, buf, GenMarkdownOptions{ShowFlagOrigins: true}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+	checkStringContains(t, output, "--config string      config file (from app)\n")
+	checkStringContains(t, output, "--namespace string   namespace of the resources (from app get)\n")
+	checkStringContains(t, output, "--all    list all the pods\n")
+
+	buf.Reset()
+	opts := GenMarkdownOptions{
+		ShowFlagOrigins: true,
+		TypeLinkHandler: func(typeName string) string { return "#" + typeName },
+	}
+	if err := GenMarkdownFromOpts(pods, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	output = buf.String()
+	checkStringContains(t, output, "* `--namespace` [string](#string): namespace of the resources (from app get)\n")
+	checkStringContains(t, output, "* `--all`: list all the pods\n")
+}
+
+func TestGenMdShellExamples(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{
+		Use:     "get",
+		Example: "app get ./config",
+		Annotations: map[string]string{
+			ExamplesByShellAnnotation: `{"powershell": "app get .\\config", "bash": "app get ./config\n"}`,
+		},
+		Run: emptyRun,
+	}
+	root.AddCommand(get)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(get, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "### Examples\n\n"+
+		"**bash**\n\n```bash\napp get ./config\n```\n\n"+
+		"**powershell**\n\n```powershell\napp get .\\config\n```\n\n")
+
+	buf.Reset()
+	opts := GenMarkdownOptions{ExampleTabs: &TabShortcodes{
+		Begin:    "{{< tabs >}}",
+		End:      "{{< /tabs >}}",
+		TabBegin: `{{< tab "%s" >}}`,
+		TabEnd:   "{{< /tab >}}",
+	}}
+	if err := GenMarkdownFromOpts(get, buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "### Examples\n\n{{< tabs >}}\n"+
+		"{{< tab \"bash\" >}}\n```bash\napp get ./config\n```\n{{< /tab >}}\n"+
+		"{{< tab \"powershell\" >}}\n```powershell\napp get .\\config\n```\n{{< /tab >}}\n"+
+		"{{< /tabs >}}\n\n")
+}
+
+func TestGenMdShellExamplesFallback(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{
+		Use:     "get",
+		Example: "app get ./config",
+		Run:     emptyRun,
+	}
+	root.AddCommand(get)
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(get, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "### Examples\n\n```\napp get ./config\n```\n\n")
+
+	get.Annotations = map[string]string{ExamplesByShellAnnotation: "app get ./config"}
+	if err := GenMarkdown(get, new(bytes.Buffer)); err == nil {
+		t.Error("expected an error for an examples_by_shell annotation which is not a JSON object")
+	}
+}
@@ -0,0 +1,43 @@
+package doc
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// plainTextSeparator separates the help of the commands in GenPlainTextTree.
+const plainTextSeparator = "\f\n"
+
+// GenPlainTextTree writes to w the help of cmd and all its descendants, e.g. for
+// grepping offline, exactly as printed by --help: with the help templates and
+// functions of the commands. The help of each command is separated from the previous
+// one by a form feed line. The hidden and deprecated commands are skipped.
+func GenPlainTextTree(cmd *cobra.Command, w io.Writer) error {
+	cmd.InitDefaultHelpCmd()
+	return genPlainTextTree(cmd, w, true)
+}
+
+func genPlainTextTree(cmd *cobra.Command, w io.Writer, first bool) error {
+	cmd.InitDefaultHelpFlag()
+	cmd.InitDefaultVersionFlag()
+
+	if !first {
+		if _, err := io.WriteString(w, plainTextSeparator); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, cmd.HelpString()); err != nil {
+		return err
+	}
+
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		if err := genPlainTextTree(c, w, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
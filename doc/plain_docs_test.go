@@ -0,0 +1,43 @@
+package doc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenPlainTextTree(t *testing.T) {
+	root := &cobra.Command{Use: "app", Short: "The app", Run: emptyRun}
+	get := &cobra.Command{Use: "get", Short: "Get a resource", Run: emptyRun}
+	hidden := &cobra.Command{Use: "secret", Short: "A hidden command", Hidden: true, Run: emptyRun}
+	root.AddCommand(get, hidden)
+	get.Flags().Bool("all", false, "get all the resources")
+
+	buf := new(bytes.Buffer)
+	if err := GenPlainTextTree(root, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	pages := strings.Split(output, "\f\n")
+	if len(pages) != 2 {
+		t.Fatalf("Expected the help of 2 commands, got %d:\n%s", len(pages), output)
+	}
+
+	// The help is the one printed by --help.
+	root.SetArgs([]string{"get", "--help"})
+	expected := new(bytes.Buffer)
+	root.SetOut(expected)
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if pages[1] != expected.String() {
+		t.Errorf("Expected the help of get to be:\n%s\ngot:\n%s", expected.String(), pages[1])
+	}
+
+	checkStringContains(t, pages[0], "The app")
+	checkStringContains(t, pages[0], "help        Help about any command")
+	checkStringOmits(t, output, "A hidden command")
+}
@@ -0,0 +1,79 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// RecipesAnnotation is the command annotation holding the recipes of the command, a
+// JSON array of tasks and the command lines performing them, e.g.
+// `[{"task": "List the pods as JSON", "command": "app get pods -o json"}]`, shown by
+// GenRecipes.
+const RecipesAnnotation = "recipes"
+
+// Recipe is a task performed with a command, and the command line performing it.
+type Recipe struct {
+	Task    string `json:"task"`
+	Command string `json:"command"`
+}
+
+// GenRecipes writes in Markdown a task-oriented page of the recipes of the tree of
+// cmd, read from their RecipesAnnotation, grouped by command under headings linking
+// to the pages of their commands. The links are built like those of
+// GenMarkdownCustom, through linkHandler. Nothing is written if no command has recipes.
+func GenRecipes(cmd *cobra.Command, w io.Writer, linkHandler func(string) string) error {
+	if linkHandler == nil {
+		linkHandler = func(s string) string { return s }
+	}
+
+	buf := new(bytes.Buffer)
+	if err := genRecipes(cmd, buf, linkHandler); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func genRecipes(cmd *cobra.Command, buf *bytes.Buffer, linkHandler func(string) string) error {
+	recipes, err := commandRecipes(cmd)
+	if err != nil {
+		return err
+	}
+	if len(recipes) > 0 {
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		path := cmd.CommandPath()
+		buf.WriteString(fmt.Sprintf("## [%s](%s)\n\n", path, linkHandler(mdDefaultLinkHandler(path))))
+		for _, recipe := range recipes {
+			buf.WriteString(fmt.Sprintf("* %s:\n\n  ```\n  %s\n  ```\n", recipe.Task, recipe.Command))
+		}
+	}
+
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		if err := genRecipes(c, buf, linkHandler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commandRecipes returns the recipes of cmd, parsed from its RecipesAnnotation.
+func commandRecipes(cmd *cobra.Command) ([]Recipe, error) {
+	value, ok := cmd.Annotations[RecipesAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var recipes []Recipe
+	if err := json.Unmarshal([]byte(value), &recipes); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation of %q: %v", RecipesAnnotation, cmd.CommandPath(), err)
+	}
+	return recipes, nil
+}
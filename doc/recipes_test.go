@@ -0,0 +1,64 @@
+package doc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenRecipes(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	get := &cobra.Command{
+		Use: "get",
+		Annotations: map[string]string{
+			RecipesAnnotation: `[{"task": "List the pods as JSON", "command": "app get pods -o json"},
+				{"task": "Watch a pod", "command": "app get pod web --watch"}]`,
+		},
+		Run: emptyRun,
+	}
+	hidden := &cobra.Command{
+		Use:         "debug",
+		Hidden:      true,
+		Annotations: map[string]string{RecipesAnnotation: `[{"task": "Dump the state", "command": "app debug"}]`},
+		Run:         emptyRun,
+	}
+	root.AddCommand(get, hidden)
+
+	buf := new(bytes.Buffer)
+	if err := GenRecipes(root, buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "## [app get](app_get.md)\n\n")
+	checkStringContains(t, output, "* List the pods as JSON:\n\n  ```\n  app get pods -o json\n  ```\n")
+	checkStringContains(t, output, "* Watch a pod:\n\n  ```\n  app get pod web --watch\n  ```\n")
+	checkStringOmits(t, output, "Dump the state")
+}
+
+func TestGenRecipesEmpty(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	root.AddCommand(&cobra.Command{Use: "get", Run: emptyRun})
+
+	buf := new(bytes.Buffer)
+	if err := GenRecipes(root, buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("Expected no output, got %q", buf.String())
+	}
+}
+
+func TestGenRecipesInvalidAnnotation(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	root.AddCommand(&cobra.Command{
+		Use:         "get",
+		Annotations: map[string]string{RecipesAnnotation: "List the pods"},
+		Run:         emptyRun,
+	})
+
+	if err := GenRecipes(root, new(bytes.Buffer), nil); err == nil {
+		t.Error("Expected an error for the invalid recipes annotation")
+	}
+}
@@ -19,7 +19,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
@@ -28,20 +27,18 @@ import (
 
 func printOptionsReST(buf *bytes.Buffer, cmd *cobra.Command, name string) error {
 	flags := cmd.NonInheritedFlags()
-	flags.SetOutput(buf)
 	if flags.HasAvailableFlags() {
 		buf.WriteString("Options\n")
 		buf.WriteString("~~~~~~~\n\n::\n\n")
-		flags.PrintDefaults()
+		buf.WriteString(cobra.FlagUsages(flags))
 		buf.WriteString("\n")
 	}
 
 	parentFlags := cmd.InheritedFlags()
-	parentFlags.SetOutput(buf)
 	if parentFlags.HasAvailableFlags() {
 		buf.WriteString("Options inherited from parent commands\n")
 		buf.WriteString("~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~\n\n::\n\n")
-		parentFlags.PrintDefaults()
+		buf.WriteString(cobra.FlagUsages(parentFlags))
 		buf.WriteString("\n")
 	}
 	return nil
@@ -66,7 +63,7 @@ func GenReSTCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string, str
 	name := cmd.CommandPath()
 
 	short := cmd.Short
-	long := cmd.Long
+	long := cmd.LongText()
 	if len(long) == 0 {
 		long = short
 	}
@@ -84,16 +81,31 @@ func GenReSTCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string, str
 		buf.WriteString(fmt.Sprintf("::\n\n  %s\n\n", cmd.UseLine()))
 	}
 
-	if len(cmd.Example) > 0 {
+	if stdin := strings.TrimSpace(cmd.Annotations[StdinAnnotation]); len(stdin) > 0 {
+		buf.WriteString("Input\n")
+		buf.WriteString("~~~~~\n\n")
+		buf.WriteString(stdin + "\n\n")
+	}
+	if stdout := strings.TrimSpace(cmd.Annotations[StdoutAnnotation]); len(stdout) > 0 {
+		buf.WriteString("Output\n")
+		buf.WriteString("~~~~~~\n\n")
+		buf.WriteString(stdout + "\n\n")
+	}
+
+	if example := cmd.ExampleText(); len(example) > 0 {
 		buf.WriteString("Examples\n")
 		buf.WriteString("~~~~~~~~\n\n")
-		buf.WriteString(fmt.Sprintf("::\n\n%s\n\n", indentString(cmd.Example, "  ")))
+		buf.WriteString(fmt.Sprintf("::\n\n%s\n\n", indentString(example, "  ")))
 	}
 
 	if err := printOptionsReST(buf, cmd, name); err != nil {
 		return err
 	}
-	if hasSeeAlso(cmd) {
+	links, err := externalLinks(cmd)
+	if err != nil {
+		return err
+	}
+	if hasSeeAlso(cmd) || len(links) > 0 {
 		buf.WriteString("SEE ALSO\n")
 		buf.WriteString("~~~~~~~~\n\n")
 		if cmd.HasParent() {
@@ -108,8 +120,7 @@ func GenReSTCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string, str
 			})
 		}
 
-		children := cmd.Commands()
-		sort.Sort(byName(children))
+		children := sortedCommands(cmd)
 
 		for _, child := range children {
 			if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
@@ -119,12 +130,15 @@ func GenReSTCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string, str
 			ref = strings.Replace(cname, " ", "_", -1)
 			buf.WriteString(fmt.Sprintf("* %s \t - %s\n", linkHandler(cname, ref), child.Short))
 		}
+		for _, link := range links {
+			buf.WriteString(fmt.Sprintf("* `%s <%s>`_\n", link.Title, link.URL))
+		}
 		buf.WriteString("\n")
 	}
 	if !cmd.DisableAutoGenTag {
 		buf.WriteString("*Auto generated by spf13/cobra on " + time.Now().Format("2-Jan-2006") + "*\n")
 	}
-	_, err := buf.WriteTo(w)
+	_, err = buf.WriteTo(w)
 	return err
 }
 
@@ -14,6 +14,7 @@
 package doc
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -44,6 +45,16 @@ func forceMultiLine(s string) string {
 	return s
 }
 
+// sortedCommands returns the child commands of cmd sorted by name, unless their order
+// is set with SetCommandSort.
+func sortedCommands(cmd *cobra.Command) []*cobra.Command {
+	children := cmd.Commands()
+	if !cmd.HasCommandSort() {
+		sort.Sort(byName(children))
+	}
+	return children
+}
+
 type byName []*cobra.Command
 
 func (s byName) Len() int           { return len(s) }
@@ -18,7 +18,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -96,11 +95,9 @@ func GenYamlCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string) str
 	yamlDoc.Name = cmd.CommandPath()
 
 	yamlDoc.Synopsis = forceMultiLine(cmd.Short)
-	yamlDoc.Description = forceMultiLine(cmd.Long)
+	yamlDoc.Description = forceMultiLine(cmd.LongText())
 
-	if len(cmd.Example) > 0 {
-		yamlDoc.Example = cmd.Example
-	}
+	yamlDoc.Example = cmd.ExampleText()
 
 	flags := cmd.NonInheritedFlags()
 	if flags.HasFlags() {
@@ -111,20 +108,26 @@ func GenYamlCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string) str
 		yamlDoc.InheritedOptions = genFlagResult(flags)
 	}
 
-	if hasSeeAlso(cmd) {
+	links, err := externalLinks(cmd)
+	if err != nil {
+		return err
+	}
+	if hasSeeAlso(cmd) || len(links) > 0 {
 		result := []string{}
 		if cmd.HasParent() {
 			parent := cmd.Parent()
 			result = append(result, parent.CommandPath()+" - "+parent.Short)
 		}
-		children := cmd.Commands()
-		sort.Sort(byName(children))
+		children := sortedCommands(cmd)
 		for _, child := range children {
 			if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
 				continue
 			}
 			result = append(result, child.Name()+" - "+child.Short)
 		}
+		for _, link := range links {
+			result = append(result, link.Title+" - "+link.URL)
+		}
 		yamlDoc.SeeAlso = result
 	}
 
@@ -0,0 +1,124 @@
+package cobra
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ErrorFormatFlagName is the name of the flag registered by AddErrorFormatFlag.
+const ErrorFormatFlagName = "error-format"
+
+// The formats of the errors printed by Execute, selected with the flag registered
+// by AddErrorFormatFlag.
+const (
+	ErrorFormatHuman = "human"
+	ErrorFormatJSON  = "json"
+)
+
+// ExitError is an error carrying the code the program should exit with.
+// The code is included in the errors printed in the JSON format.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// errorFormatValue is a flag value accepting only the known error formats.
+type errorFormatValue string
+
+func (v *errorFormatValue) String() string {
+	return string(*v)
+}
+
+func (v *errorFormatValue) Set(s string) error {
+	if s != ErrorFormatHuman && s != ErrorFormatJSON {
+		return fmt.Errorf("must be %q or %q", ErrorFormatHuman, ErrorFormatJSON)
+	}
+	*v = errorFormatValue(s)
+	return nil
+}
+
+func (v *errorFormatValue) Type() string {
+	return "string"
+}
+
+// AddErrorFormatFlag registers the persistent --error-format flag on cmd, selecting
+// how Execute prints the errors: "human", the default, prints them as usual, while
+// "json" prints them to stderr as a single JSON object for scripts, e.g.
+// {"error":"file not found","command":"app get","code":3}, without the usage.
+// The code is only present when the error is, or wraps, an ExitError.
+func AddErrorFormatFlag(cmd *Command) {
+	value := errorFormatValue(ErrorFormatHuman)
+	cmd.PersistentFlags().Var(&value, ErrorFormatFlagName, `format of the errors, "human" or "json"`)
+	_ = cmd.RegisterFlagCompletionFunc(ErrorFormatFlagName, func(*Command, []string, string) ([]string, ShellCompDirective) {
+		return []string{ErrorFormatHuman, ErrorFormatJSON}, ShellCompDirectiveNoFileComp
+	})
+}
+
+// errorFormat returns the error format selected with the flag registered by
+// AddErrorFormatFlag. As errors may happen before the flags are parsed, e.g. for
+// unknown commands, the arguments are searched for the flag when it is not set.
+func (c *Command) errorFormat(args []string) string {
+	c.mergePersistentFlags()
+	f := c.Flags().Lookup(ErrorFormatFlagName)
+	if f == nil {
+		return ErrorFormatHuman
+	}
+	if _, ok := f.Value.(*errorFormatValue); !ok {
+		return ErrorFormatHuman
+	}
+	if f.Changed {
+		return f.Value.String()
+	}
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		value := ""
+		if arg == "--"+ErrorFormatFlagName && i+1 < len(args) {
+			value = args[i+1]
+		} else if strings.HasPrefix(arg, "--"+ErrorFormatFlagName+"=") {
+			value = strings.TrimPrefix(arg, "--"+ErrorFormatFlagName+"=")
+		}
+		if value == ErrorFormatHuman || value == ErrorFormatJSON {
+			return value
+		}
+	}
+	return f.DefValue
+}
+
+// jsonError is the JSON representation of the errors printed by Execute.
+type jsonError struct {
+	Error   string `json:"error"`
+	Command string `json:"command"`
+	Code    *int   `json:"code,omitempty"`
+}
+
+// printJSONError prints err, returned by cmd, to stderr in the JSON format.
+func (c *Command) printJSONError(cmd *Command, err error) {
+	jsonErr := jsonError{Error: err.Error(), Command: cmd.CommandPath()}
+	if exitErr := findExitError(err); exitErr != nil {
+		jsonErr.Code = &exitErr.Code
+	}
+	out, _ := json.Marshal(jsonErr)
+	fmt.Fprintln(c.ErrOrStderr(), string(out))
+}
+
+// findExitError returns the first ExitError of err and the errors it wraps, or nil.
+func findExitError(err error) *ExitError {
+	for ; err != nil; err = unwrapError(err) {
+		if exitErr, ok := err.(*ExitError); ok {
+			return exitErr
+		}
+	}
+	return nil
+}
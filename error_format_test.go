@@ -0,0 +1,91 @@
+package cobra
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestErrorFormatHuman(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	getCmd := &Command{
+		Use: "get",
+		RunE: func(*Command, []string) error {
+			return &ExitError{Code: 3, Err: fmt.Errorf("file not found")}
+		},
+	}
+	rootCmd.AddCommand(getCmd)
+	AddErrorFormatFlag(rootCmd)
+
+	output, err := executeCommand(rootCmd, "get")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, output, "Error: file not found\n")
+	checkStringContains(t, output, "Usage:")
+}
+
+func TestErrorFormatJSON(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	getCmd := &Command{
+		Use: "get",
+		RunE: func(*Command, []string) error {
+			return &ExitError{Code: 3, Err: fmt.Errorf("file not found")}
+		},
+	}
+	rootCmd.AddCommand(getCmd)
+	AddErrorFormatFlag(rootCmd)
+
+	output, err := executeCommand(rootCmd, "get", "--error-format", "json")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	expected := `{"error":"file not found","command":"app get","code":3}` + "\n"
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestErrorFormatJSONWrappedExitError(t *testing.T) {
+	rootCmd := &Command{
+		Use: "app",
+		RunE: func(*Command, []string) error {
+			return causeError{&ExitError{Code: 4, Err: fmt.Errorf("timeout")}}
+		},
+	}
+	AddErrorFormatFlag(rootCmd)
+
+	output, err := executeCommand(rootCmd, "--error-format", "json")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	expected := `{"error":"wrapped: timeout","command":"app","code":4}` + "\n"
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestErrorFormatJSONUnknownCommand(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "get", Run: emptyRun})
+	AddErrorFormatFlag(rootCmd)
+
+	output, err := executeCommand(rootCmd, "--error-format=json", "delete")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	expected := `{"error":"unknown command \"delete\" for \"app\"","command":"app"}` + "\n"
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestErrorFormatInvalid(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	AddErrorFormatFlag(rootCmd)
+
+	output, err := executeCommand(rootCmd, "--error-format", "xml")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, output, `invalid argument "xml" for "--error-format" flag: must be "human" or "json"`)
+}
@@ -53,6 +53,12 @@ function __%[1]s_perform_completion
     __%[1]s_debug "flagPrefix: $flagPrefix"
 
     for comp in $comps
+        # Placeholders (an empty value followed by a hint) cannot be
+        # displayed by fish without being selectable, so skip them.
+        if string match -q -r -- '^\t' "$comp"
+            __%[1]s_debug "Skipping placeholder: $comp"
+            continue
+        end
         printf "%%s%%s\n" "$flagPrefix" "$comp"
     end
 
@@ -0,0 +1,113 @@
+package cobra
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// FlagDependencyAnnotation is the flag annotation listing, as "name=value" entries,
+// the flags set implicitly when the annotated flag is set.
+const FlagDependencyAnnotation = "cobra_annotation_flag_dependency"
+
+// SetFlagDependency declares that setting the trigger flag implies setting the
+// dependent flag to value, e.g. setting "--tls-cert" implies "--tls=true".
+// The dependent flag is set after the flags are parsed, before the flag groups are
+// validated and the *PreRun functions are called. If the user explicitly set the
+// dependent flag to a different value, or another flag implies a different value
+// of it, the command fails with a usage error.
+// Dependencies are applied transitively and are shown by the doc generators.
+func (c *Command) SetFlagDependency(trigger, dependent, value string) error {
+	triggerFlag := c.Flag(trigger)
+	if triggerFlag == nil {
+		return fmt.Errorf("SetFlagDependency: flag '%s' does not exist", trigger)
+	}
+	dependentFlag := c.Flag(dependent)
+	if dependentFlag == nil {
+		return fmt.Errorf("SetFlagDependency: flag '%s' does not exist", dependent)
+	}
+	if triggerFlag == dependentFlag {
+		return fmt.Errorf("SetFlagDependency: flag '%s' cannot depend on itself", trigger)
+	}
+	if triggerFlag.Annotations == nil {
+		triggerFlag.Annotations = map[string][]string{}
+	}
+	triggerFlag.Annotations[FlagDependencyAnnotation] = append(triggerFlag.Annotations[FlagDependencyAnnotation], dependent+"="+value)
+	return nil
+}
+
+// FlagDependencies returns the "name=value" flag settings implied by setting f.
+func FlagDependencies(f *flag.Flag) []string {
+	return f.Annotations[FlagDependencyAnnotation]
+}
+
+// applyFlagDependencies sets the flags implied by the flags set on the command-line.
+func (c *Command) applyFlagDependencies() error {
+	flags := c.Flags()
+
+	explicit := map[string]bool{}
+	impliedBy := map[string]string{} // trigger of each implied flag
+	var pending []*flag.Flag
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+		pending = append(pending, f)
+	})
+
+	for len(pending) > 0 {
+		trigger := pending[0]
+		pending = pending[1:]
+		for _, dependency := range FlagDependencies(trigger) {
+			i := strings.Index(dependency, "=")
+			if i < 0 {
+				continue
+			}
+			name, value := dependency[:i], dependency[i+1:]
+			dependent := flags.Lookup(name)
+			if dependent == nil {
+				continue
+			}
+			if dependent.Changed {
+				if current := dependent.Value.String(); current != normalizedFlagValue(dependent, value) {
+					if explicit[name] {
+						return fmt.Errorf("flag \"--%s\" requires \"--%s=%s\" but \"--%s=%s\" was given",
+							trigger.Name, name, value, name, current)
+					}
+					return fmt.Errorf("flag \"--%s\" requires \"--%s=%s\" but flag \"--%s\" requires \"--%s=%s\"",
+						trigger.Name, name, value, impliedBy[name], name, current)
+				}
+				continue
+			}
+			if err := flags.Set(name, value); err != nil {
+				return fmt.Errorf("flag \"--%s\" could not set \"--%s=%s\": %v", trigger.Name, name, value, err)
+			}
+			impliedBy[name] = trigger.Name
+			pending = append(pending, dependent)
+		}
+	}
+	return nil
+}
+
+// normalizedFlagValue returns value as printed by the value of f once set to it, e.g.
+// "true" for "1" for a boolean flag, by parsing it with a new value of the same type.
+// It returns value unchanged if it cannot be parsed so, e.g. for the values of types
+// which are not of a basic kind, such as slices.
+func normalizedFlagValue(f *flag.Flag, value string) string {
+	typ := reflect.TypeOf(f.Value)
+	if typ.Kind() != reflect.Ptr {
+		return value
+	}
+	switch typ.Elem().Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return value
+	}
+	parsed, ok := reflect.New(typ.Elem()).Interface().(flag.Value)
+	if !ok || parsed.Set(value) != nil {
+		return value
+	}
+	return parsed.String()
+}
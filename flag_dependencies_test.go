@@ -0,0 +1,131 @@
+package cobra
+
+import (
+	"testing"
+)
+
+func TestFlagDependency(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().String("tls-cert", "", "")
+	c.Flags().Bool("tls", false, "")
+	c.Flags().Bool("verify", false, "")
+	if err := c.SetFlagDependency("tls-cert", "tls", "true"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.SetFlagDependency("tls", "verify", "true"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := executeCommand(c, "--tls-cert", "cert.pem"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tls, _ := c.Flags().GetBool("tls"); !tls {
+		t.Error("Expected --tls to be set by --tls-cert")
+	}
+	if verify, _ := c.Flags().GetBool("verify"); !verify {
+		t.Error("Expected --verify to be set transitively by --tls-cert")
+	}
+}
+
+func TestFlagDependencyNotTriggered(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().String("tls-cert", "", "")
+	c.Flags().Bool("tls", false, "")
+	if err := c.SetFlagDependency("tls-cert", "tls", "true"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := executeCommand(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tls, _ := c.Flags().GetBool("tls"); tls {
+		t.Error("Expected --tls to be left unset")
+	}
+}
+
+func TestFlagDependencyConflict(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().String("tls-cert", "", "")
+	c.Flags().Bool("tls", false, "")
+	if err := c.SetFlagDependency("tls-cert", "tls", "true"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output, err := executeCommand(c, "--tls-cert", "cert.pem", "--tls=false")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if !IsUsageError(err) {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+	checkStringContains(t, output, `flag "--tls-cert" requires "--tls=true" but "--tls=false" was given`)
+}
+
+func TestFlagDependencySameValue(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().String("tls-cert", "", "")
+	c.Flags().Bool("tls", false, "")
+	c.Flags().Duration("timeout", 0, "")
+	if err := c.SetFlagDependency("tls-cert", "tls", "1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.SetFlagDependency("tls-cert", "timeout", "60s"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Explicitly setting the same value, even written differently, is not a conflict.
+	if _, err := executeCommand(c, "--tls-cert", "cert.pem", "--tls", "--timeout", "1m"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestFlagDependencyConflictBetweenTriggers(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().Bool("fast", false, "")
+	c.Flags().Bool("safe", false, "")
+	c.Flags().String("mode", "", "")
+	if err := c.SetFlagDependency("fast", "mode", "fast"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.SetFlagDependency("safe", "mode", "safe"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output, err := executeCommand(c, "--safe", "--fast")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if !IsUsageError(err) {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+	checkStringContains(t, output, `flag "--safe" requires "--mode=safe" but flag "--fast" requires "--mode=fast"`)
+}
+
+func TestFlagDependencyMutuallyExclusive(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().String("tls-cert", "", "")
+	c.Flags().Bool("tls", false, "")
+	c.Flags().Bool("insecure", false, "")
+	c.MarkFlagsMutuallyExclusive("tls", "insecure")
+	if err := c.SetFlagDependency("tls-cert", "tls", "true"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The implied --tls is validated against the flag groups too.
+	output, err := executeCommand(c, "--tls-cert", "cert.pem", "--insecure")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, output, "were all set")
+}
+
+func TestSetFlagDependencyUnknownFlag(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().Bool("tls", false, "")
+	if err := c.SetFlagDependency("unknown", "tls", "true"); err == nil {
+		t.Error("Expected an error for an unknown trigger flag")
+	}
+	if err := c.SetFlagDependency("tls", "unknown", "true"); err == nil {
+		t.Error("Expected an error for an unknown dependent flag")
+	}
+}
@@ -0,0 +1,153 @@
+package cobra
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// MutuallyExclusiveFlagsAnnotation is the flag annotation listing the groups of
+// mutually exclusive flags the annotated flag belongs to, each as the names of its
+// flags separated by spaces.
+const MutuallyExclusiveFlagsAnnotation = "cobra_annotation_mutually_exclusive"
+
+// FlagGroupAnnotation is the flag annotation holding the name of the group of the flag,
+// added with AddFlagGroup.
+const FlagGroupAnnotation = "cobra_annotation_flag_group"
+
+// AddFlagGroup adds the given local flags of the command to the named group, e.g.
+// "Connection" or "Output", to list them under their own heading, "Connection Flags:",
+// in the help and in the generated docs. The groups are listed in the order they are
+// added, after the flags which are not part of any group.
+func (c *Command) AddFlagGroup(name string, flagNames ...string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("AddFlagGroup: the group needs a name")
+	}
+	for _, flagName := range flagNames {
+		f := c.LocalFlags().Lookup(flagName)
+		if f == nil {
+			return fmt.Errorf("AddFlagGroup: flag '%s' does not exist", flagName)
+		}
+		if f.Annotations == nil {
+			f.Annotations = map[string][]string{}
+		}
+		f.Annotations[FlagGroupAnnotation] = []string{name}
+	}
+	if !stringInSlice(name, c.flagGroups) {
+		c.flagGroups = append(c.flagGroups, name)
+	}
+	return nil
+}
+
+// FlagGroups returns the names of the groups of the local flags of the command, in the
+// order they were added with AddFlagGroup.
+func (c *Command) FlagGroups() []string {
+	return c.flagGroups
+}
+
+// LocalFlagsInGroup returns the local flags of the command which are part of the named
+// group, or which are not part of any group if name is empty.
+func (c *Command) LocalFlagsInGroup(name string) *flag.FlagSet {
+	local := c.LocalFlags()
+	flags := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	flags.SortFlags = local.SortFlags
+	local.VisitAll(func(f *flag.Flag) {
+		group := ""
+		if groups := f.Annotations[FlagGroupAnnotation]; len(groups) > 0 && stringInSlice(groups[0], c.flagGroups) {
+			group = groups[0]
+		}
+		if group == name {
+			flags.AddFlag(f)
+		}
+	})
+	return flags
+}
+
+// MarkFlagsMutuallyExclusive declares that at most one of the given flags can be set,
+// e.g. "json" and "yaml". The command fails with a usage error if several of them are
+// set on the command-line, and the completion of the flag names leaves out the flags
+// conflicting with those already set.
+func (c *Command) MarkFlagsMutuallyExclusive(flagNames ...string) error {
+	if len(flagNames) < 2 {
+		return fmt.Errorf("MarkFlagsMutuallyExclusive: a group needs at least 2 flags, got %d", len(flagNames))
+	}
+	c.mergePersistentFlags()
+	var flags []*flag.Flag
+	for _, name := range flagNames {
+		f := c.Flags().Lookup(name)
+		if f == nil {
+			return fmt.Errorf("MarkFlagsMutuallyExclusive: flag '%s' does not exist", name)
+		}
+		flags = append(flags, f)
+	}
+	group := strings.Join(flagNames, " ")
+	for _, f := range flags {
+		if f.Annotations == nil {
+			f.Annotations = map[string][]string{}
+		}
+		f.Annotations[MutuallyExclusiveFlagsAnnotation] = append(f.Annotations[MutuallyExclusiveFlagsAnnotation], group)
+	}
+	return nil
+}
+
+// mutuallyExclusiveFlagGroups returns the groups of mutually exclusive flags of flags,
+// each as the names of its flags, sorted for a deterministic validation.
+func mutuallyExclusiveFlagGroups(flags *flag.FlagSet) [][]string {
+	seen := map[string]bool{}
+	var keys []string
+	flags.VisitAll(func(f *flag.Flag) {
+		for _, group := range f.Annotations[MutuallyExclusiveFlagsAnnotation] {
+			if !seen[group] {
+				seen[group] = true
+				keys = append(keys, group)
+			}
+		}
+	})
+	sort.Strings(keys)
+
+	groups := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		groups = append(groups, strings.Split(key, " "))
+	}
+	return groups
+}
+
+// validateFlagGroups returns an error if several flags of a group of mutually exclusive
+// flags are set.
+func (c *Command) validateFlagGroups() error {
+	flags := c.Flags()
+	for _, group := range mutuallyExclusiveFlagGroups(flags) {
+		var set []string
+		for _, name := range group {
+			if f := flags.Lookup(name); f != nil && f.Changed {
+				set = append(set, name)
+			}
+		}
+		if len(set) > 1 {
+			return fmt.Errorf("if any flags in the group [%s] are set none of the others can be; [%s] were all set",
+				strings.Join(group, " "), strings.Join(set, " "))
+		}
+	}
+	return nil
+}
+
+// conflictingFlags returns the names of the flags of flags which cannot be set because
+// another flag of one of their groups of mutually exclusive flags is already set.
+func conflictingFlags(flags *flag.FlagSet) map[string]bool {
+	conflicts := map[string]bool{}
+	for _, group := range mutuallyExclusiveFlagGroups(flags) {
+		for _, name := range group {
+			if f := flags.Lookup(name); f == nil || !f.Changed {
+				continue
+			}
+			for _, other := range group {
+				if other != name {
+					conflicts[other] = true
+				}
+			}
+		}
+	}
+	return conflicts
+}
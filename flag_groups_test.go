@@ -0,0 +1,188 @@
+package cobra
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkFlagsMutuallyExclusive(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().Bool("json", false, "output as JSON")
+	c.Flags().Bool("yaml", false, "output as YAML")
+	c.Flags().Bool("verbose", false, "verbose output")
+	if err := c.MarkFlagsMutuallyExclusive("json", "yaml"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := executeCommand(c, "--json", "--verbose"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestMarkFlagsMutuallyExclusiveConflict(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().Bool("json", false, "output as JSON")
+	c.Flags().Bool("yaml", false, "output as YAML")
+	c.Flags().Bool("verbose", false, "verbose output")
+	if err := c.MarkFlagsMutuallyExclusive("json", "yaml"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err := executeCommand(c, "--json", "--yaml")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if !IsUsageError(err) {
+		t.Errorf("Expected a usage error, got %T", err)
+	}
+	expected := "if any flags in the group [json yaml] are set none of the others can be; [json yaml] were all set"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+}
+
+func TestMarkFlagsMutuallyExclusiveUnknownFlag(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().Bool("json", false, "")
+	if err := c.MarkFlagsMutuallyExclusive("json", "yaml"); err == nil {
+		t.Error("Expected an error for the unknown flag")
+	}
+	if err := c.MarkFlagsMutuallyExclusive("json"); err == nil {
+		t.Error("Expected an error for a group of a single flag")
+	}
+}
+
+func TestMarkFlagsMutuallyExclusivePersistent(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.PersistentFlags().Bool("json", false, "output as JSON")
+	c.PersistentFlags().Bool("yaml", false, "output as YAML")
+	if err := c.MarkFlagsMutuallyExclusive("json", "yaml"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := executeCommand(c, "--json", "--yaml"); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestFlagNameCompletionMutuallyExclusive(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().Bool("json", false, "output as JSON")
+	c.Flags().Bool("yaml", false, "output as YAML")
+	c.Flags().Bool("verbose", false, "verbose output")
+	if err := c.MarkFlagsMutuallyExclusive("json", "yaml"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output, err := executeCommand(c, ShellCompNoDescRequestCmd, "--json", "--")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// --yaml conflicts with --json, which is set and so listed last.
+	expected := strings.Join([]string{
+		"--help",
+		"--verbose",
+		"--json",
+		":0",
+		"Completion ended with directive: ShellCompDirectiveDefault", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestFlagNameCompletionWithoutFlagGroups(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().Bool("json", false, "output as JSON")
+	c.Flags().Bool("yaml", false, "output as YAML")
+
+	output, err := executeCommand(c, ShellCompNoDescRequestCmd, "--")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"--help",
+		"--json",
+		"--yaml",
+		":0",
+		"Completion ended with directive: ShellCompDirectiveDefault", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestAddFlagGroupHelp(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().String("host", "", "server host")
+	c.Flags().Int("port", 443, "server port")
+	c.Flags().String("output", "text", "output format")
+	c.Flags().Bool("verbose", false, "verbose output")
+	if err := c.AddFlagGroup("Connection", "host", "port"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.AddFlagGroup("Output", "output"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output, err := executeCommand(c, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	checkStringContains(t, output, "Flags:\n"+
+		"  -h, --help      help for c\n"+
+		"      --verbose   verbose output\n"+
+		"\n"+
+		"Connection Flags:\n"+
+		"      --host string   server host\n"+
+		"      --port int      server port (default 443)\n"+
+		"\n"+
+		"Output Flags:\n"+
+		"      --output string   output format (default \"text\")\n")
+}
+
+func TestAddFlagGroupAllFlagsGrouped(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().String("host", "", "server host")
+	c.InitDefaultHelpFlag()
+	if err := c.AddFlagGroup("Connection", "host", "help"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output, err := executeCommand(c, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	checkStringOmits(t, output, "\nFlags:")
+	checkStringContains(t, output, "\nConnection Flags:\n")
+}
+
+func TestAddFlagGroupUnknownFlag(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	if err := c.AddFlagGroup("Connection", "host"); err == nil {
+		t.Error("Expected an error for the unknown flag")
+	}
+}
+
+func TestCompletionForMutuallyExclusiveKeepsFlags(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().Bool("json", false, "output as JSON")
+	c.Flags().Bool("yaml", false, "output as YAML")
+	c.Flags().Bool("verbose", false, "verbose output")
+	if err := c.MarkFlagsMutuallyExclusive("json", "yaml"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	completions, _, err := c.CompletionFor([]string{"--json"}, "--")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, comp := range completions {
+		if comp.Value == "--yaml" {
+			t.Errorf("Expected --yaml to be left out, got %v", completions)
+		}
+	}
+	if f := c.Flags().Lookup("json"); f.Changed || f.Value.String() != "false" {
+		t.Errorf("Expected the completion to leave --json unset, got %q", f.Value.String())
+	}
+}
@@ -0,0 +1,90 @@
+package cobra
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	flag "github.com/spf13/pflag"
+)
+
+// The sources of the values of the flags, as returned by FlagSource.
+const (
+	FlagSourceDefault = "default"
+	FlagSourceFlag    = "flag"
+	FlagSourceEnv     = "env"
+	FlagSourceConfig  = "config"
+)
+
+// FlagSourceAnnotation is the flag annotation recording the source of the value of
+// a flag set with SetFlagFromSource.
+const FlagSourceAnnotation = "cobra_annotation_flag_source"
+
+// DebugFlagsFlagName is the name of the flag registered by AddDebugFlagsFlag.
+const DebugFlagsFlagName = "debug-flags"
+
+// SetFlagFromSource sets the value of the named flag of c, like Flags().Set, and
+// records where the value comes from, e.g. FlagSourceEnv for a value read from an
+// environment variable or FlagSourceConfig for one read from a configuration file,
+// as returned by FlagSource. Call it once the flags are parsed, e.g. in
+// PersistentPreRunE, for the flags which were not set on the command line.
+func (c *Command) SetFlagFromSource(name, value, source string) error {
+	f := c.Flag(name)
+	if f == nil {
+		return fmt.Errorf("SetFlagFromSource: flag '%s' does not exist", name)
+	}
+	if err := c.Flags().Set(f.Name, value); err != nil {
+		return err
+	}
+	if f.Annotations == nil {
+		f.Annotations = map[string][]string{}
+	}
+	f.Annotations[FlagSourceAnnotation] = []string{source}
+	return nil
+}
+
+// FlagSource returns the source of the value of f: the one recorded by
+// SetFlagFromSource, FlagSourceFlag if it was set on the command line, or
+// FlagSourceDefault.
+func FlagSource(f *flag.Flag) string {
+	if source := f.Annotations[FlagSourceAnnotation]; len(source) > 0 {
+		return source[0]
+	}
+	if f.Changed {
+		return FlagSourceFlag
+	}
+	return FlagSourceDefault
+}
+
+// AddDebugFlagsFlag registers the persistent --debug-flags flag on cmd, e.g. for
+// support tickets. When it is set, the command prints a table of all its effective
+// flags with their values and sources, as returned by FlagSource, instead of
+// running. The table is printed once the flags are parsed and the pre-runs, which
+// may set flags from other sources, have run. The values of the sensitive flags
+// are redacted.
+func AddDebugFlagsFlag(cmd *Command) {
+	cmd.PersistentFlags().Bool(DebugFlagsFlagName, false, "print the values of the flags and their sources instead of running")
+}
+
+// debugFlagsRequested returns true if the flag registered by AddDebugFlagsFlag is set.
+func (c *Command) debugFlagsRequested() bool {
+	f := c.Flags().Lookup(DebugFlagsFlagName)
+	return f != nil && f.Changed && f.Value.String() == "true"
+}
+
+// printFlagSources prints the table of the effective flags of c, with their values
+// and sources, to stdout.
+func (c *Command) printFlagSources() error {
+	w := tabwriter.NewWriter(c.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FLAG\tVALUE\tSOURCE")
+	c.Flags().VisitAll(func(f *flag.Flag) {
+		if f.Name == DebugFlagsFlagName {
+			return
+		}
+		value := f.Value.String()
+		if isFlagSensitive(f) && f.Changed && len(value) > 0 {
+			value = RedactedValue
+		}
+		fmt.Fprintf(w, "--%s\t%s\t%s\n", f.Name, value, FlagSource(f))
+	})
+	return w.Flush()
+}
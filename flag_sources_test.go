@@ -0,0 +1,48 @@
+package cobra
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDebugFlags(t *testing.T) {
+	rootCmd := &Command{
+		Use: "root",
+		PersistentPreRunE: func(cmd *Command, args []string) error {
+			if value, ok := os.LookupEnv("COBRA_TEST_NAMESPACE"); ok && !cmd.Flags().Changed("namespace") {
+				return cmd.SetFlagFromSource("namespace", value, FlagSourceEnv)
+			}
+			return nil
+		},
+	}
+	AddDebugFlagsFlag(rootCmd)
+	rootCmd.PersistentFlags().String("namespace", "default", "")
+	ran := false
+	getCmd := &Command{Use: "get", Run: func(*Command, []string) { ran = true }}
+	getCmd.Flags().String("output", "text", "")
+	getCmd.Flags().Int("limit", 10, "")
+	rootCmd.AddCommand(getCmd)
+
+	os.Setenv("COBRA_TEST_NAMESPACE", "staging")
+	defer os.Unsetenv("COBRA_TEST_NAMESPACE")
+
+	output, err := executeCommand(rootCmd, "--debug-flags", "get", "--limit", "5")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if ran {
+		t.Error("Expected the command not to run")
+	}
+	checkStringContains(t, output, "FLAG         VALUE    SOURCE\n")
+	checkStringContains(t, output, "--namespace  staging  env\n")
+	checkStringContains(t, output, "--output     text     default\n")
+	checkStringContains(t, output, "--limit      5        flag\n")
+	checkStringOmits(t, output, "--debug-flags")
+}
+
+func TestSetFlagFromSourceUnknownFlag(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	if err := c.SetFlagFromSource("namespace", "staging", FlagSourceEnv); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+}
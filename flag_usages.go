@@ -0,0 +1,123 @@
+package cobra
+
+import (
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// FlagTypeNames translates the type names of flag values shown in the help output
+// and the generated documentation, e.g. {"int": "entier"} for a French CLI. It is
+// keyed by the names returned by the Type() method of the flag values. The type
+// names which are not in it are shown untranslated, which is the default.
+var FlagTypeNames = map[string]string{}
+
+// FlagTypeName returns the translation in FlagTypeNames of the given flag type name,
+// or the type name itself if it has no translation.
+func FlagTypeName(typeName string) string {
+	if name, ok := FlagTypeNames[typeName]; ok {
+		return name
+	}
+	return typeName
+}
+
+// EnableBoolFlagSwitches renders the boolean flags as simple switches in the help
+// output, e.g. "--verbose", without their default value nor the value they take
+// when given without one. The generated documentation is not affected.
+var EnableBoolFlagSwitches = false
+
+// FlagUsages returns the usage of the flags in the set like its FlagUsages method,
+// with the type names translated as set in FlagTypeNames.
+func FlagUsages(flags *flag.FlagSet) string {
+	return flagUsages(flags, false)
+}
+
+// helpFlagUsages returns the usage of the flags in the set for the help output.
+// It is available as the "flagUsages" function in the usage and help templates.
+func helpFlagUsages(flags *flag.FlagSet) string {
+	return flagUsages(flags, EnableBoolFlagSwitches)
+}
+
+// flagUsages returns the usage of the flags in the set as rendered by pflag, with the
+// flags replaced by copies translating their type names and, if boolSwitches is set,
+// rendering the boolean flags as switches.
+func flagUsages(flags *flag.FlagSet, boolSwitches bool) string {
+	if len(FlagTypeNames) == 0 && !boolSwitches {
+		return flags.FlagUsages()
+	}
+
+	rendered := flag.NewFlagSet("", flag.ContinueOnError)
+	rendered.SortFlags = flags.SortFlags
+	flags.VisitAll(func(f *flag.Flag) {
+		rendered.AddFlag(usageFlag(f, boolSwitches))
+	})
+	return rendered.FlagUsages()
+}
+
+// usageFlag returns a copy of f for flagUsages.
+func usageFlag(f *flag.Flag, boolSwitches bool) *flag.Flag {
+	usage := *f
+	typ := f.Value.Type()
+	if boolSwitches && typ == "bool" {
+		// pflag shows neither the default of a boolean flag if it is false, nor
+		// the value it takes when given without one if it is true.
+		usage.DefValue = "false"
+		usage.NoOptDefVal = "true"
+		return &usage
+	}
+
+	name, ok := FlagTypeNames[typ]
+	if varname, _ := flag.UnquoteUsage(f); !ok || varname == "" || strings.Contains(f.Usage, "`") {
+		return &usage
+	}
+	// pflag names the value of a flag after its type, but also uses the type to
+	// format the values and to tell whether the default is shown; the formatting
+	// is done beforehand for the translated type.
+	usage.Value = &typeNameValue{Value: f.Value, typeName: name, defaultShown: showsDefault(f)}
+	switch typ {
+	case "string":
+		usage.DefValue = strconv.Quote(f.DefValue)
+		if f.NoOptDefVal != "" {
+			usage.NoOptDefVal = `"` + f.NoOptDefVal + `"`
+		}
+	case "count":
+		if f.NoOptDefVal == "+1" {
+			usage.NoOptDefVal = ""
+		}
+	}
+	return &usage
+}
+
+// showsDefault returns true if pflag shows the default value of f in its usage.
+func showsDefault(f *flag.Flag) bool {
+	bare := *f
+	bare.Usage = ""
+	bare.Deprecated = ""
+	bare.Hidden = false
+	single := flag.NewFlagSet("", flag.ContinueOnError)
+	single.AddFlag(&bare)
+	return strings.HasSuffix(strings.TrimSpace(single.FlagUsages()), ")")
+}
+
+// typeNameValue is a flag value with a translated type name, only used to render
+// the usage of the flag.
+type typeNameValue struct {
+	flag.Value
+	typeName     string
+	defaultShown bool
+}
+
+func (v *typeNameValue) Type() string {
+	return v.typeName
+}
+
+// String is only called by pflag to tell whether the default value of the flag is
+// shown, which it cannot tell from the type of the value; the empty string makes it
+// hide the default value.
+func (v *typeNameValue) String() string {
+	if v.defaultShown {
+		return "shown"
+	}
+	return ""
+}
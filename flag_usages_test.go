@@ -0,0 +1,83 @@
+package cobra
+
+import "testing"
+
+func TestFlagUsagesTranslatedTypeNames(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().IntP("count", "n", 3, "number of items")
+	c.Flags().Duration("timeout", 0, "time to wait")
+	c.Flags().String("name", "", "the `identifier` to use")
+	c.Flags().Bool("force", false, "force the operation")
+	c.Flags().Float64("ratio", 0, "ratio")
+	c.Flags().String("label", "new", "label of the item")
+	c.Flags().String("mode", "", "the mode")
+	c.Flags().Lookup("mode").NoOptDefVal = "auto"
+
+	expected := c.Flags().FlagUsages()
+	if got := FlagUsages(c.Flags()); got != expected {
+		t.Errorf("Expected the pflag usages without translations:\n%s\ngot:\n%s", expected, got)
+	}
+
+	defer func(names map[string]string) { FlagTypeNames = names }(FlagTypeNames)
+	FlagTypeNames = map[string]string{
+		"int":      "entier",
+		"duration": "durée",
+		"string":   "chaîne",
+		"bool":     "booléen",
+		"float64":  "réel",
+	}
+
+	output, err := executeCommand(c, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "-n, --count entier")
+	checkStringContains(t, output, "(default 3)")
+	checkStringContains(t, output, "--timeout durée")
+	checkStringOmits(t, output, "(default 0s)")
+	checkStringContains(t, output, "--ratio réel")
+	checkStringContains(t, FlagUsages(c.Flags()), "--label chaîne")
+	checkStringContains(t, FlagUsages(c.Flags()), "label of the item (default \"new\")")
+	checkStringContains(t, FlagUsages(c.Flags()), "--mode chaîne[=\"auto\"]")
+	// Names given in the usage and boolean flags are not affected.
+	checkStringContains(t, output, "--name identifier")
+	checkStringOmits(t, output, "booléen")
+	checkStringOmits(t, output, " int ")
+
+	if name := FlagTypeName("duration"); name != "durée" {
+		t.Errorf("Expected translated type name, got %q", name)
+	}
+	if name := FlagTypeName("ip"); name != "ip" {
+		t.Errorf("Expected untranslated type name, got %q", name)
+	}
+}
+
+func TestBoolFlagSwitchesInHelp(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	c.Flags().Bool("color", true, "colorize the output")
+	c.Flags().Bool("verbose", false, "verbose output")
+	c.Flags().Lookup("verbose").NoOptDefVal = "false"
+	c.Flags().Int("count", 3, "number of items")
+
+	output, err := executeCommand(c, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "(default true)")
+	checkStringContains(t, output, "--verbose[=false]")
+
+	defer func() { EnableBoolFlagSwitches = false }()
+	EnableBoolFlagSwitches = true
+
+	output, err = executeCommand(c, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringOmits(t, output, "(default true)")
+	checkStringOmits(t, output, "[=false]")
+	checkStringContains(t, output, "--color ")
+	checkStringContains(t, output, "(default 3)")
+
+	// The usages for the docs are not affected.
+	checkStringContains(t, FlagUsages(c.Flags()), "(default true)")
+}
@@ -0,0 +1,167 @@
+package cobra
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// FlagSensitiveAnnotation is the flag annotation marking the flags whose values are
+// secrets, set by MarkFlagSensitive.
+const FlagSensitiveAnnotation = "cobra_annotation_flag_sensitive"
+
+// RedactedValue replaces the values of the sensitive flags in errors and reconstructed
+// invocations.
+const RedactedValue = "[REDACTED]"
+
+// MarkFlagSensitive marks the named flag as holding a secret, such as a password.
+// Its value is then replaced by RedactedValue in the errors returned by Execute,
+// e.g. the errors of the parsing of the flags, which are also the errors printed,
+// and in ReconstructInvocation.
+func (c *Command) MarkFlagSensitive(name string) error {
+	f := c.Flag(name)
+	if f == nil {
+		return fmt.Errorf("MarkFlagSensitive: flag '%s' does not exist", name)
+	}
+	if f.Annotations == nil {
+		f.Annotations = map[string][]string{}
+	}
+	f.Annotations[FlagSensitiveAnnotation] = []string{"true"}
+	return nil
+}
+
+// isFlagSensitive returns true if f was marked with MarkFlagSensitive.
+func isFlagSensitive(f *flag.Flag) bool {
+	return len(f.Annotations[FlagSensitiveAnnotation]) > 0
+}
+
+// minRedactedTokenLen is the minimum length of the values of the sensitive flags
+// redacted where they appear unquoted in an error, so that short values, such as "a"
+// or "1", do not redact unrelated words.
+const minRedactedTokenLen = 4
+
+// redactedError is an error whose message has the values of the sensitive flags
+// redacted. It wraps the original error, so that it still matches IsUsageError and
+// the ExitError it may wrap, but only the redacted message is printed.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the original error, whose message is not redacted.
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactSensitiveValues returns err with the values given to the sensitive flags of c,
+// in args or once parsed, replaced by RedactedValue in its message. A value is replaced
+// where it appears quoted, as in the errors of the parsing of the flags, or as a whole
+// word if it is at least minRedactedTokenLen long.
+func (c *Command) redactSensitiveValues(err error, args []string) error {
+	if err == nil {
+		return nil
+	}
+	values := c.sensitiveValues(args)
+	if len(values) == 0 {
+		return err
+	}
+
+	msg := err.Error()
+	for _, value := range values {
+		msg = strings.Replace(msg, strconv.Quote(value), RedactedValue, -1)
+		if len(value) >= minRedactedTokenLen {
+			msg = replaceToken(msg, value, RedactedValue)
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+// replaceToken replaces the occurrences of old in s which are not part of a longer
+// word with new.
+func replaceToken(s, old, new string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, old)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(old)
+		if (i > 0 && isWordByte(s[i-1])) || (end < len(s) && isWordByte(s[end])) {
+			b.WriteString(s[:end])
+		} else {
+			b.WriteString(s[:i])
+			b.WriteString(new)
+		}
+		s = s[end:]
+	}
+}
+
+// isWordByte returns true if b is a letter, a digit or an underscore.
+func isWordByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// sensitiveValues returns the non-empty values of the sensitive flags of c, as set
+// once parsed, and as given in args, which may not have been parsed successfully.
+func (c *Command) sensitiveValues(args []string) []string {
+	c.mergePersistentFlags()
+	flags := c.Flags()
+
+	var values []string
+	add := func(value string) {
+		if len(value) > 0 && !stringInSlice(value, values) {
+			values = append(values, value)
+		}
+	}
+	flags.VisitAll(func(f *flag.Flag) {
+		// The values of the flags which do not need one, such as booleans, are no secrets.
+		if isFlagSensitive(f) && f.Changed && len(f.NoOptDefVal) == 0 {
+			add(f.Value.String())
+		}
+	})
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+
+		var f *flag.Flag
+		var value string
+		hasValue := false
+		if strings.HasPrefix(arg, "--") {
+			name := arg[2:]
+			if eq := strings.Index(name, "="); eq >= 0 {
+				name, value, hasValue = name[:eq], name[eq+1:], true
+			}
+			f = flags.Lookup(name)
+		} else {
+			f = flags.ShorthandLookup(arg[1:2])
+			if len(arg) > 2 {
+				value, hasValue = strings.TrimPrefix(arg[2:], "="), true
+			}
+		}
+		if f == nil || !isFlagSensitive(f) || len(f.NoOptDefVal) > 0 {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		add(value)
+	}
+	return values
+}
@@ -0,0 +1,121 @@
+package cobra
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMarkFlagSensitiveParseError(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	loginCmd := &Command{Use: "login", Run: emptyRun}
+	loginCmd.Flags().IntP("pin", "p", 0, "")
+	rootCmd.AddCommand(loginCmd)
+
+	if err := loginCmd.MarkFlagSensitive("unknown"); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+	if err := loginCmd.MarkFlagSensitive("pin"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"login", "--pin", "s3cr3t"},
+		{"login", "--pin=s3cr3t"},
+		{"login", "-p", "s3cr3t"},
+		{"login", "-ps3cr3t"},
+	} {
+		output, err := executeCommand(rootCmd, args...)
+		if err == nil {
+			t.Fatalf("%v: expected an error", args)
+		}
+		if !IsUsageError(err) {
+			t.Errorf("%v: expected a usage error, got %v", args, err)
+		}
+		checkStringOmits(t, err.Error(), "s3cr3t")
+		checkStringOmits(t, output, "s3cr3t")
+		checkStringContains(t, err.Error(), RedactedValue)
+	}
+}
+
+func TestMarkFlagSensitiveRunError(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	loginCmd := &Command{
+		Use: "login",
+		RunE: func(cmd *Command, args []string) error {
+			password, _ := cmd.Flags().GetString("password")
+			return &ExitError{Code: 4, Err: fmt.Errorf("wrong password %s", password)}
+		},
+	}
+	loginCmd.Flags().String("password", "", "")
+	_ = loginCmd.MarkFlagSensitive("password")
+	rootCmd.AddCommand(loginCmd)
+
+	_, err := executeCommand(rootCmd, "login", "--password", "hunter2")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if err.Error() != "wrong password "+RedactedValue {
+		t.Errorf("Expected the password to be redacted, got %q", err.Error())
+	}
+	if exitErr := findExitError(err); exitErr == nil || exitErr.Code != 4 {
+		t.Errorf("Expected the exit code to be kept, got %#v", err)
+	}
+}
+
+func TestMarkFlagSensitiveKeepsCause(t *testing.T) {
+	appErr := fmt.Errorf("cannot log in with 1 as the pin 1")
+	rootCmd := &Command{
+		Use:  "app",
+		RunE: func(*Command, []string) error { return appErr },
+	}
+	rootCmd.Flags().String("pin", "", "")
+	_ = rootCmd.MarkFlagSensitive("pin")
+
+	_, err := executeCommand(rootCmd, "--pin", "1")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	// The value is too short to be redacted outside of quotes.
+	if err != appErr {
+		t.Errorf("Expected the error to be returned unchanged, got %q", err.Error())
+	}
+
+	rootCmd.RunE = func(*Command, []string) error { return fmt.Errorf("neither pin12 nor pin1 work") }
+	_, err = executeCommand(rootCmd, "--pin", "pin1")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if expected := "neither pin12 nor " + RedactedValue + " work"; err.Error() != expected {
+		t.Errorf("Expected only the whole value to be redacted: %q, got %q", expected, err.Error())
+	}
+
+	usageErr := newUsageError(fmt.Errorf("invalid pin %q", "1234"))
+	rootCmd.RunE = func(*Command, []string) error { return usageErr }
+	_, err = executeCommand(rootCmd, "--pin", "1234")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if err.Error() != "invalid pin "+RedactedValue {
+		t.Errorf("Expected the pin to be redacted, got %q", err.Error())
+	}
+	if unwrapError(err) != usageErr || !IsUsageError(err) {
+		t.Errorf("Expected the redacted error to wrap the original one, got %#v", err)
+	}
+}
+
+func TestReconstructInvocationSensitiveFlag(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	loginCmd := &Command{Use: "login", Run: emptyRun}
+	loginCmd.Flags().String("user", "", "")
+	loginCmd.Flags().String("password", "", "")
+	_ = loginCmd.MarkFlagSensitive("password")
+	rootCmd.AddCommand(loginCmd)
+
+	if _, err := executeCommand(rootCmd, "login", "--user", "alice", "--password", "hunter2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "app login --password " + RedactedValue + " --user alice"
+	if got := loginCmd.ReconstructInvocation(); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+}
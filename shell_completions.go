@@ -1,19 +1,30 @@
 package cobra
 
 import (
+	"path/filepath"
+
 	"github.com/spf13/pflag"
 )
 
 // MarkFlagRequired adds the BashCompOneRequiredFlag annotation to the named flag if it exists,
 // and causes your command to report an error if invoked without the flag.
 func (c *Command) MarkFlagRequired(name string) error {
-	return MarkFlagRequired(c.Flags(), name)
+	return c.markFlagRequired(c.Flags(), name)
 }
 
 // MarkPersistentFlagRequired adds the BashCompOneRequiredFlag annotation to the named persistent flag if it exists,
 // and causes your command to report an error if invoked without the flag.
 func (c *Command) MarkPersistentFlagRequired(name string) error {
-	return MarkFlagRequired(c.PersistentFlags(), name)
+	return c.markFlagRequired(c.PersistentFlags(), name)
+}
+
+// markFlagRequired marks the named flag of flags as required, and records its name
+// for ValidateTree if it does not exist.
+func (c *Command) markFlagRequired(flags *pflag.FlagSet, name string) error {
+	if flags.Lookup(name) == nil {
+		c.missingRequiredFlags = append(c.missingRequiredFlags, name)
+	}
+	return MarkFlagRequired(flags, name)
 }
 
 // MarkFlagRequired adds the BashCompOneRequiredFlag annotation to the named flag if it exists,
@@ -83,3 +94,21 @@ func MarkFlagDirname(flags *pflag.FlagSet, name string) error {
 	zshPattern := "-(/)"
 	return flags.SetAnnotation(name, zshCompDirname, []string{zshPattern})
 }
+
+// GenAllCompletions generates the bash, zsh, fish and PowerShell completion files of
+// the command into dir, with the conventional names "<name>.bash", "_<name>",
+// "<name>.fish" and "<name>.ps1", e.g. for packaging. includeDesc applies to the
+// shells supporting it, as for GenFishCompletionFile.
+func (c *Command) GenAllCompletions(dir string, includeDesc bool) error {
+	name := c.Name()
+	if err := c.GenBashCompletionFile(filepath.Join(dir, name+".bash")); err != nil {
+		return err
+	}
+	if err := c.GenZshCompletionFile(filepath.Join(dir, "_"+name)); err != nil {
+		return err
+	}
+	if err := c.GenFishCompletionFile(filepath.Join(dir, name+".fish"), includeDesc); err != nil {
+		return err
+	}
+	return c.GenPowerShellCompletionFile(filepath.Join(dir, name+".ps1"))
+}
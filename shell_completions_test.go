@@ -0,0 +1,52 @@
+package cobra
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenAllCompletions(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "get", Short: "Get a resource", Run: emptyRun})
+
+	tmpdir, err := ioutil.TempDir("", "test-gen-all-completions")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := rootCmd.GenAllCompletions(tmpdir, true); err != nil {
+		t.Fatalf("GenAllCompletions failed: %v", err)
+	}
+
+	generators := map[string]func(buf *bytes.Buffer) error{
+		"app.bash": func(buf *bytes.Buffer) error { return rootCmd.GenBashCompletion(buf) },
+		"_app":     func(buf *bytes.Buffer) error { return rootCmd.GenZshCompletion(buf) },
+		"app.fish": func(buf *bytes.Buffer) error { return rootCmd.GenFishCompletion(buf, true) },
+		"app.ps1":  func(buf *bytes.Buffer) error { return rootCmd.GenPowerShellCompletion(buf) },
+	}
+	for filename, gen := range generators {
+		content, err := ioutil.ReadFile(filepath.Join(tmpdir, filename))
+		if err != nil {
+			t.Errorf("Expected file %q to exist: %v", filename, err)
+			continue
+		}
+		expected := new(bytes.Buffer)
+		if err := gen(expected); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(content, expected.Bytes()) {
+			t.Errorf("Expected %q to hold the output of its generator", filename)
+		}
+	}
+}
+
+func TestGenAllCompletionsMissingDir(t *testing.T) {
+	rootCmd := &Command{Use: "app", Run: emptyRun}
+	if err := rootCmd.GenAllCompletions(filepath.Join(os.TempDir(), "cobra-missing-dir", "completions"), false); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}